@@ -5,16 +5,36 @@ package main
 import (
 	"fmt"
 	"go-ethereum-wallet/keygen"
+	"log"
 )
 
 func main() {
+	kdfParams, err := keygen.KDFParamsFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid KDF configuration: %v", err)
+	}
+
+	ks := keygen.NewKeystore(keygen.AccountPath, keygen.TerminalPasswordProvider{})
+	ks.KDFParams = kdfParams
+
 	for {
 		fmt.Println("Menu:")
 		fmt.Println("1. Create account")
 		fmt.Println("2. Get address for account")
 		fmt.Println("3. Get private key for account")
 		fmt.Println("4. Save account with existing private key")
-		fmt.Println("5. Exit")
+		fmt.Println("5. Export account to keystore v3 JSON")
+		fmt.Println("6. Import account from keystore v3 JSON")
+		fmt.Println("7. Create account from new mnemonic")
+		fmt.Println("8. Restore account from mnemonic")
+		fmt.Println("9. Create HD account")
+		fmt.Println("10. List HD account addresses")
+		fmt.Println("11. Migrate account files to the latest format")
+		fmt.Println("12. Benchmark key derivation")
+		fmt.Println("13. Change account password")
+		fmt.Println("14. List accounts")
+		fmt.Println("15. Sign transaction file offline")
+		fmt.Println("16. Exit")
 		fmt.Print("Enter your choice: ")
 
 		var choice int
@@ -22,14 +42,36 @@ func main() {
 
 		switch choice {
 		case 1:
-			keygen.CreateAccount()
+			createAccount(ks)
 		case 2:
-			keygen.GetAddressForAccount()
+			getAddressForAccount(ks)
 		case 3:
-			keygen.GetPrivateKeyForAccount()
+			getPrivateKeyForAccount(ks)
 		case 4:
-			keygen.SaveAccountWithPrivateKey()
+			saveAccountWithPrivateKey(ks)
 		case 5:
+			exportAccountToKeystoreV3(ks)
+		case 6:
+			importAccountFromKeystoreV3(ks)
+		case 7:
+			createAccountFromMnemonic(ks)
+		case 8:
+			restoreAccountFromMnemonic(ks)
+		case 9:
+			createHDAccount(ks)
+		case 10:
+			listHDAddresses(ks)
+		case 11:
+			migrateAccount(ks)
+		case 12:
+			benchmarkKDF()
+		case 13:
+			changeAccountPassword(ks)
+		case 14:
+			listAccounts(ks)
+		case 15:
+			signTransactionFile(ks)
+		case 16:
 			return
 		default:
 			fmt.Println("Invalid choice. Please try again.")
@@ -0,0 +1,432 @@
+// keygen/menu.go
+
+package main
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-ethereum-wallet/keygen"
+	"go-ethereum-wallet/transfer/config"
+	"go-ethereum-wallet/transfer/offline"
+	"go-ethereum-wallet/transfer/registry"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func createAccount(ks *keygen.Keystore) {
+	accountName := readAccountName()
+
+	account, err := ks.CreateAccount(accountName)
+	if err != nil {
+		log.Fatalf("Failed to create account: %v", err)
+	}
+
+	fmt.Printf("Public Address: %s\n", account.Address.Hex())
+	fmt.Printf("Account '%s' successfully saved to '%s'\n", accountName, keygen.AccountPath)
+}
+
+func getAddressForAccount(ks *keygen.Keystore) {
+	account, err := ks.Account(readAccountName())
+	if err != nil {
+		log.Fatalf("Failed to get account: %v", err)
+	}
+
+	fmt.Printf("Public Address: %s\n", account.Address.Hex())
+}
+
+func getPrivateKeyForAccount(ks *keygen.Keystore) {
+	privateKey, err := ks.PrivateKey(readAccountName())
+	if err != nil {
+		log.Fatalf("Failed to retrieve private key: %v", err)
+	}
+
+	fmt.Printf("Private Key: %s\n", hex.EncodeToString(crypto.FromECDSA(privateKey)))
+}
+
+func saveAccountWithPrivateKey(ks *keygen.Keystore) {
+	accountName := readAccountName()
+
+	fmt.Print("Enter private key (hex format): ")
+	var privateKeyHex string
+	fmt.Scanln(&privateKeyHex)
+
+	privateKeyBytes, err := hex.DecodeString(privateKeyHex)
+	if err != nil {
+		log.Fatalf("Failed to decode private key: %v", err)
+	}
+
+	account, err := ks.ImportPrivateKey(accountName, privateKeyBytes)
+	if err != nil {
+		log.Fatalf("Failed to save account: %v", err)
+	}
+
+	fmt.Printf("Public Address: %s\n", account.Address.Hex())
+	fmt.Printf("Account '%s' successfully saved to '%s'\n", accountName, keygen.AccountPath)
+}
+
+func exportAccountToKeystoreV3(ks *keygen.Keystore) {
+	accountName := readAccountName()
+
+	exportPassword, err := keygen.ReadPassword("Enter a password to encrypt the keystore file: ")
+	if err != nil {
+		log.Fatalf("Failed to read password: %v", err)
+	}
+
+	keyJSON, err := ks.ExportKeystoreV3(accountName, exportPassword)
+	if err != nil {
+		log.Fatalf("Failed to export account: %v", err)
+	}
+
+	filePath := filepath.Join(keygen.AccountPath, accountName+".json")
+	err = os.WriteFile(filePath, keyJSON, 0600)
+	if err != nil {
+		log.Fatalf("Failed to save keystore file: %v", err)
+	}
+
+	fmt.Printf("Keystore file successfully saved to '%s'\n", filePath)
+}
+
+func importAccountFromKeystoreV3(ks *keygen.Keystore) {
+	accountName := readAccountName()
+
+	fmt.Print("Enter path to the keystore file: ")
+	var keystorePath string
+	fmt.Scanln(&keystorePath)
+
+	keyJSON, err := os.ReadFile(keystorePath)
+	if err != nil {
+		log.Fatalf("Failed to read keystore file: %v", err)
+	}
+
+	keystorePassword, err := keygen.ReadPassword("Enter the keystore file password: ")
+	if err != nil {
+		log.Fatalf("Failed to read password: %v", err)
+	}
+
+	account, err := ks.ImportKeystoreV3(accountName, keyJSON, keystorePassword)
+	if err != nil {
+		log.Fatalf("Failed to import account: %v", err)
+	}
+
+	fmt.Printf("Public Address: %s\n", account.Address.Hex())
+	fmt.Printf("Account '%s' successfully saved to '%s'\n", accountName, keygen.AccountPath)
+}
+
+func createAccountFromMnemonic(ks *keygen.Keystore) {
+	accountName := readAccountName()
+
+	fmt.Print("Enter number of words (12 or 24): ")
+	var words int
+	fmt.Scanln(&words)
+
+	mnemonic, err := keygen.NewMnemonic(words)
+	if err != nil {
+		log.Fatalf("Failed to generate mnemonic: %v", err)
+	}
+	printMnemonic(mnemonic)
+
+	saveMnemonicAccount(ks, accountName, mnemonic)
+}
+
+func restoreAccountFromMnemonic(ks *keygen.Keystore) {
+	accountName := readAccountName()
+
+	fmt.Print("Enter mnemonic: ")
+	mnemonic, err := readMnemonic()
+	if err != nil {
+		log.Fatalf("Failed to read mnemonic: %v", err)
+	}
+
+	saveMnemonicAccount(ks, accountName, mnemonic)
+}
+
+func saveMnemonicAccount(ks *keygen.Keystore, accountName, mnemonic string) {
+	passphrase, err := keygen.ReadPassword("Enter mnemonic passphrase (optional): ")
+	if err != nil {
+		log.Fatalf("Failed to read passphrase: %v", err)
+	}
+
+	account, err := ks.CreateMnemonicAccount(accountName, mnemonic, passphrase)
+	if err != nil {
+		log.Fatalf("Failed to save account: %v", err)
+	}
+
+	fmt.Printf("Derivation Path: %s\n", keygen.ChildPath(account.Path, 0).String())
+	fmt.Printf("Public Address: %s\n", account.Address.Hex())
+	fmt.Printf("Account '%s' successfully saved to '%s'\n", accountName, keygen.AccountPath)
+}
+
+func createHDAccount(ks *keygen.Keystore) {
+	accountName := readAccountName()
+
+	fmt.Print("Enter mnemonic (leave empty to generate a new 24-word mnemonic): ")
+	mnemonic, err := readMnemonic()
+	if err != nil {
+		log.Fatalf("Failed to read mnemonic: %v", err)
+	}
+
+	if mnemonic == "" {
+		mnemonic, err = keygen.NewMnemonic(24)
+		if err != nil {
+			log.Fatalf("Failed to generate mnemonic: %v", err)
+		}
+		printMnemonic(mnemonic)
+	}
+
+	passphrase, err := keygen.ReadPassword("Enter mnemonic passphrase (optional): ")
+	if err != nil {
+		log.Fatalf("Failed to read passphrase: %v", err)
+	}
+
+	fmt.Printf("Enter base derivation path (leave empty for %s): ", keygen.DefaultHDBasePath.String())
+	var pathInput string
+	fmt.Scanln(&pathInput)
+
+	basePath := keygen.DefaultHDBasePath
+	if pathInput != "" {
+		basePath, err = accounts.ParseDerivationPath(pathInput)
+		if err != nil {
+			log.Fatalf("Invalid derivation path: %v", err)
+		}
+	}
+
+	account, err := ks.CreateHDAccount(accountName, mnemonic, passphrase, basePath)
+	if err != nil {
+		log.Fatalf("Failed to create HD account: %v", err)
+	}
+
+	fmt.Printf("First Address (%s): %s\n", keygen.ChildPath(account.Path, 0).String(), account.Address.Hex())
+	fmt.Printf("Account '%s' successfully saved to '%s'\n", accountName, keygen.AccountPath)
+}
+
+func listHDAddresses(ks *keygen.Keystore) {
+	accountName := readAccountName()
+
+	fmt.Print("Enter number of addresses: ")
+	var count uint32
+	fmt.Scanln(&count)
+
+	basePath, err := ks.DerivationPath(accountName)
+	if err != nil {
+		log.Fatalf("Failed to load derivation path: %v", err)
+	}
+
+	addresses, err := ks.DeriveAddresses(accountName, 0, count)
+	if err != nil {
+		log.Fatalf("Failed to derive addresses: %v", err)
+	}
+
+	for i, address := range addresses {
+		fmt.Printf("%d: %s %s\n", i, keygen.ChildPath(basePath, uint32(i)).String(), address.Hex())
+	}
+}
+
+func migrateAccount(ks *keygen.Keystore) {
+	accountName := readAccountName()
+
+	migrated, err := ks.Migrate(accountName)
+	if err != nil {
+		log.Fatalf("Failed to migrate account: %v", err)
+	}
+
+	if len(migrated) == 0 {
+		fmt.Printf("Account '%s' already uses format version %d with %s\n", accountName, keygen.FormatVersion, ks.KDFParams.Name)
+		return
+	}
+	for _, filePath := range migrated {
+		fmt.Printf("Upgraded '%s' to format version %d with %s\n", filePath, keygen.FormatVersion, ks.KDFParams.Name)
+	}
+}
+
+func benchmarkKDF() {
+	fmt.Print("Enter target unlock time in milliseconds: ")
+	var targetMillis int
+	fmt.Scanln(&targetMillis)
+	if targetMillis <= 0 {
+		log.Fatalf("Target unlock time must be greater than zero")
+	}
+	target := time.Duration(targetMillis) * time.Millisecond
+
+	argon2Params, argon2Duration, err := keygen.SuggestArgon2idParams(target)
+	switch {
+	case errors.Is(err, keygen.ErrBenchmarkLimit):
+		fmt.Printf("No %s suggestion: %v\n", keygen.KDFArgon2id, err)
+	case err != nil:
+		log.Fatalf("Failed to benchmark %s: %v", keygen.KDFArgon2id, err)
+	default:
+		fmt.Printf("%s: t=%d m=%d KiB p=%d unlocks in %s\n", keygen.KDFArgon2id, argon2Params.T, argon2Params.M, argon2Params.P, argon2Duration.Round(time.Millisecond))
+		fmt.Printf("  %s=%s %s=%d %s=%d %s=%d\n", keygen.EnvKDF, keygen.KDFArgon2id, keygen.EnvArgon2Time, argon2Params.T, keygen.EnvArgon2Memory, argon2Params.M, keygen.EnvKDFThreads, argon2Params.P)
+	}
+
+	scryptParams, scryptDuration, err := keygen.SuggestScryptParams(target)
+	switch {
+	case errors.Is(err, keygen.ErrBenchmarkLimit):
+		fmt.Printf("No %s suggestion: %v\n", keygen.KDFScrypt, err)
+	case err != nil:
+		log.Fatalf("Failed to benchmark %s: %v", keygen.KDFScrypt, err)
+	default:
+		fmt.Printf("%s: n=%d r=%d p=%d unlocks in %s\n", keygen.KDFScrypt, scryptParams.N, scryptParams.R, scryptParams.P, scryptDuration.Round(time.Millisecond))
+		fmt.Printf("  %s=%s %s=%d %s=%d\n", keygen.EnvKDF, keygen.KDFScrypt, keygen.EnvScryptN, scryptParams.N, keygen.EnvScryptR, scryptParams.R)
+	}
+}
+
+func changeAccountPassword(ks *keygen.Keystore) {
+	rewritten, err := ks.ChangePassword(readAccountName())
+	if err != nil {
+		log.Fatalf("Failed to change password: %v", err)
+	}
+
+	for _, filePath := range rewritten {
+		fmt.Printf("Re-encrypted '%s'\n", filePath)
+	}
+}
+
+func listAccounts(ks *keygen.Keystore) {
+	accountList, err := ks.ListAccounts()
+	if err != nil {
+		log.Fatalf("Failed to list accounts: %v", err)
+	}
+
+	if len(accountList) == 0 {
+		fmt.Printf("No accounts found in '%s'\n", keygen.AccountPath)
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tADDRESS\tCREATED\tSOURCE\tVERSION")
+	for _, account := range accountList {
+		address := "unknown (migrate to read)"
+		if account.Address != (common.Address{}) {
+			address = account.Address.Hex()
+		}
+		source := account.Source
+		if source == "" {
+			source = "unknown"
+		}
+		if account.Path != nil {
+			source += " (" + account.Path.String() + ")"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\n", account.Name, address, account.Created.Format(time.RFC3339), source, account.Version)
+	}
+	w.Flush()
+}
+
+func signTransactionFile(ks *keygen.Keystore) {
+	fmt.Print("Enter path to the unsigned transaction file: ")
+	var unsignedPath string
+	fmt.Scanln(&unsignedPath)
+
+	unsigned, err := offline.ReadUnsigned(unsignedPath)
+	if err != nil {
+		log.Fatalf("Failed to read unsigned transaction: %v", err)
+	}
+	if err := unsigned.CheckSummary(readTokenRegistry(unsigned.ChainID)); err != nil {
+		log.Fatalf("Refusing to sign: %v", err)
+	}
+	decoded, err := unsigned.Decoded()
+	if err != nil {
+		log.Fatalf("Failed to decode transaction: %v", err)
+	}
+	maxFee, err := unsigned.MaxFee()
+	if err != nil {
+		log.Fatalf("Failed to compute fee: %v", err)
+	}
+
+	fmt.Printf("Chain ID: %d\n", unsigned.ChainID)
+	fmt.Printf("From: %s (nonce %d)\n", unsigned.From, unsigned.Nonce)
+	fmt.Printf("Transfer: %s %s to %s\n", unsigned.Summary.Amount, unsigned.Summary.Asset, unsigned.Summary.Recipient)
+	if unsigned.Data != "0x" {
+		fmt.Printf("Token Contract: %s\n", unsigned.To)
+	}
+	fmt.Printf("Decoded: %s\n", decoded)
+	fmt.Printf("Maximum Fee: %s wei\n", maxFee)
+	fmt.Print("Sign this transaction? (yes/no): ")
+	var confirmation string
+	fmt.Scanln(&confirmation)
+	if confirmation != "yes" {
+		fmt.Println("Signing cancelled.")
+		return
+	}
+
+	accountName := readAccountName()
+	var privateKey *ecdsa.PrivateKey
+	if ks.IsHDAccount(accountName) {
+		fmt.Print("Enter the address index to sign with: ")
+		var index uint32
+		fmt.Scanln(&index)
+		privateKey, err = ks.DerivedPrivateKey(accountName, index)
+	} else {
+		privateKey, err = ks.PrivateKey(accountName)
+	}
+	if err != nil {
+		log.Fatalf("Failed to retrieve private key: %v", err)
+	}
+
+	signed, err := offline.Sign(unsigned, privateKey)
+	if err != nil {
+		log.Fatalf("Failed to sign transaction: %v", err)
+	}
+
+	signedPath := offline.SignedPath(unsignedPath)
+	if err := offline.WriteFile(signedPath, signed); err != nil {
+		log.Fatalf("Failed to save signed transaction: %v", err)
+	}
+	fmt.Printf("Transaction %s signed and saved to '%s'\n", signed.Hash, signedPath)
+}
+
+// readTokenRegistry loads the token registry of the built-in network on chainID, or asks for
+// a registry file for other chains. Without one, only Ether transfers can be checked.
+func readTokenRegistry(chainID uint64) *registry.Registry {
+	path := ""
+	for _, network := range config.Networks {
+		if network.ChainID == chainID {
+			path = network.TokenRegistryPath
+			break
+		}
+	}
+	if path == "" {
+		fmt.Printf("Enter path to the token registry for chain %d (blank for Ether transfers): ", chainID)
+		fmt.Scanln(&path)
+		if path == "" {
+			return nil
+		}
+	}
+
+	tokens, err := registry.Load(path)
+	if err != nil {
+		log.Fatalf("Failed to load token registry: %v", err)
+	}
+	return tokens
+}
+
+func readAccountName() string {
+	fmt.Print("Enter account name: ")
+	var accountName string
+	fmt.Scanln(&accountName)
+	return accountName
+}
+
+// readMnemonic reads a whole line of words from stdin and normalizes the spacing
+func readMnemonic() (string, error) {
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(strings.Fields(line), " "), nil
+}
+
+func printMnemonic(mnemonic string) {
+	fmt.Println("Write down the mnemonic and keep it safe. It is the only backup of this account:")
+	fmt.Println(mnemonic)
+}
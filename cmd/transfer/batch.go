@@ -0,0 +1,404 @@
+// transfer/batch.go
+
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"go-ethereum-wallet/transfer/asset"
+	"go-ethereum-wallet/transfer/batch"
+	"go-ethereum-wallet/transfer/config"
+	"go-ethereum-wallet/transfer/ethereum_client"
+	"go-ethereum-wallet/transfer/logger"
+	"go-ethereum-wallet/transfer/nonce"
+	"go-ethereum-wallet/transfer/preflight"
+	"go-ethereum-wallet/transfer/simulate"
+	"go-ethereum-wallet/transfer/transaction"
+	"go-ethereum-wallet/transfer/userinput"
+)
+
+// payout is a validated payment that still has to be sent. A payout signed in an interrupted
+// run is sent again with its earlier nonce when reuse is set.
+type payout struct {
+	index  int
+	asset  asset.Asset
+	amount *big.Int
+	price  float64
+	nonce  uint64
+	reuse  bool
+}
+
+// sendBatch pays every row of a CSV file after a single confirmation. Progress is written to a
+// results file after each row, and rerunning with the same file skips the rows already sent.
+func sendBatch(cfg config.Config, client *ethclient.Client, chainID *big.Int) {
+	tokenRegistry, assets := loadAssets(cfg, client, chainID)
+
+	paymentsPath := userinput.GetPayoutsPath()
+	payments, err := batch.ReadPayments(paymentsPath)
+	if err != nil {
+		logger.Error.Fatalf("Failed to read payouts: %v", err)
+	}
+	resultsPath := batch.ResultsPath(paymentsPath)
+	previous, err := batch.ReadResults(resultsPath)
+	if err != nil {
+		logger.Error.Fatalf("Failed to read earlier results: %v", err)
+	}
+
+	privateKey, fromAddress := unlockAccount()
+
+	priceOracle := newPriceOracle(cfg, client, tokenRegistry)
+	currency := cfg.Currency
+	prices := map[string]float64{"ETH": quotePrice(priceOracle, "ETH", currency)}
+
+	gasStrategy, fees := selectGasFees(cfg, client)
+	ethereum_client.DisplayGasPrices(gasStrategy, fees, prices["ETH"], currency)
+
+	results := make([]batch.Result, len(payments))
+	var payouts []payout
+	var invalid []string
+	done := 0
+	for i, payment := range payments {
+		results[i] = batch.Result{Payment: payment}
+
+		if earlier, ok := previous[payment.Line]; ok {
+			if !earlier.Matches(payment) {
+				logger.Error.Fatalf("Line %d of %s changed since the last run; move %s away to start over", payment.Line, paymentsPath, resultsPath)
+			}
+			if resumeResult(client, &earlier) {
+				results[i] = earlier
+				done++
+				continue
+			}
+		}
+
+		currentAsset, err := payment.Validate(assets)
+		if err != nil {
+			invalid = append(invalid, "line "+strconv.Itoa(payment.Line)+": "+err.Error())
+			continue
+		}
+		symbol := strings.ToUpper(currentAsset.Symbol())
+		if _, ok := prices[symbol]; !ok {
+			prices[symbol] = quotePrice(priceOracle, symbol, currency)
+		}
+		amount, err := payment.ParseAmount(currentAsset, prices[symbol], currency)
+		if err != nil {
+			invalid = append(invalid, "line "+strconv.Itoa(payment.Line)+": "+err.Error())
+			continue
+		}
+		payouts = append(payouts, payout{index: i, asset: currentAsset, amount: amount, price: prices[symbol]})
+	}
+
+	if len(invalid) > 0 {
+		for _, problem := range invalid {
+			logger.Error.Println(problem)
+		}
+		logger.Error.Fatalf("%d invalid row(s) in %s; nothing was sent", len(invalid), paymentsPath)
+	}
+	if len(payouts) == 0 {
+		logger.Info.Printf("All %d payouts in %s were already sent; see %s\n", done, paymentsPath, resultsPath)
+		return
+	}
+
+	nonces := nonce.NewManager(cfg.NonceStorePath, client, chainID)
+	reconciliation, err := nonces.Reconcile(context.Background(), fromAddress)
+	if err != nil {
+		logger.Error.Fatalf("Failed to reconcile nonces: %v", err)
+	}
+
+	// A row signed before the last run stopped keeps its nonce, which also fills the gap it left
+	reused := make(map[uint64]bool)
+	for i := range payouts {
+		earlier, ok := previous[results[payouts[i].index].Line]
+		if !ok {
+			continue
+		}
+		if n, ok := earlier.ResumeNonce(reconciliation.Mined, reconciliation.Pending); ok {
+			payouts[i].nonce, payouts[i].reuse = n, true
+			reused[n] = true
+			logger.Info.Printf("Line %d: resending with its earlier nonce %d\n", earlier.Line, n)
+		}
+	}
+	var gaps []uint64
+	for _, gap := range reconciliation.Gaps {
+		if !reused[gap] {
+			gaps = append(gaps, gap)
+		}
+	}
+	if len(gaps) > 0 {
+		logger.Error.Fatalf("Nonce gap at %v would hold back every payout; fill it with a single transfer first", gaps)
+	}
+
+	// Token transfers fail gas estimation without a reason when the balance is short,
+	// so check the amounts before estimating and again with the fees afterwards
+	requirements := make([]preflight.Requirement, 0, len(payouts)+1)
+	for _, p := range payouts {
+		requirements = append(requirements, preflight.Requirement{Asset: p.asset, Amount: p.amount, Price: p.price})
+	}
+	if err := preflight.Check(context.Background(), client, fromAddress, currency, requirements...); err != nil {
+		logger.Error.Fatalf("Batch not sent: %v", err)
+	}
+
+	// Estimate and simulate each payout with the next nonces so token transfers get their real
+	// gas limits and a payout that would revert stops the batch before anything is sent
+	totalFee := new(big.Int)
+	totals := make(map[string]*big.Int)
+	nextNonce := reconciliation.Next
+	for _, p := range payouts {
+		estimateNonce := p.nonce
+		if !p.reuse {
+			estimateNonce = nextNonce
+			nextNonce++
+		}
+		tx, err := p.asset.CreateTransferTransaction(client, newPayoutInput(fromAddress, results[p.index].Address, p, chainID, estimateNonce, fees, prices["ETH"]))
+		if err != nil {
+			logger.Error.Fatalf("Line %d: failed to estimate transaction: %v", results[p.index].Line, err)
+		}
+		if err := simulate.Transaction(context.Background(), client, tx, fromAddress, p.asset.ABI()); err != nil {
+			logger.Error.Fatalf("Line %d: %v; nothing was sent", results[p.index].Line, err)
+		}
+		totalFee.Add(totalFee, new(big.Int).Mul(tx.GasFeeCap(), new(big.Int).SetUint64(tx.Gas())))
+
+		symbol := strings.ToUpper(p.asset.Symbol())
+		if totals[symbol] == nil {
+			totals[symbol] = new(big.Int)
+		}
+		totals[symbol].Add(totals[symbol], p.amount)
+	}
+
+	logBatchSummary(payouts, totals, totalFee, prices, currency, done)
+
+	requirements = append(requirements, preflight.Requirement{Asset: &asset.Ether{}, Amount: totalFee, Price: prices["ETH"]})
+	if err := preflight.Check(context.Background(), client, fromAddress, currency, requirements...); err != nil {
+		logger.Error.Fatalf("Batch not sent: %v", err)
+	}
+
+	if !userinput.ConfirmBatch(len(payouts)) {
+		logger.Info.Println("Batch cancelled.")
+		return
+	}
+
+	var sent []int
+	for _, p := range payouts {
+		result := &results[p.index]
+		signedTx, err := sendPayout(cfg, client, chainID, nonces, fromAddress, privateKey, p, result, fees, prices["ETH"], func() {
+			writeResults(resultsPath, results)
+		})
+		if errors.Is(err, errBroadcastUnknown) {
+			// The row stays signed with its nonce reserved, so the next run looks the
+			// transaction up and resends it only with the same nonce
+			result.Error = err.Error()
+			writeResults(resultsPath, results)
+			logger.Error.Printf("Line %d: %v; stopping the batch, run it again to check and resume", result.Line, err)
+			break
+		}
+		if err != nil {
+			result.Status = batch.StatusFailed
+			result.Error = err.Error()
+			logger.Error.Printf("Line %d: %v", result.Line, err)
+		} else {
+			result.Status = batch.StatusSent
+			result.Error = ""
+			sent = append(sent, p.index)
+			if err := nonces.Record(fromAddress, signedTx); err != nil {
+				logger.Error.Printf("Failed to record nonce %d: %v", signedTx.Nonce(), err)
+			}
+		}
+		writeResults(resultsPath, results)
+	}
+
+	if cfg.Confirmations > 0 {
+		confirmBatch(cfg, client, results, sent, resultsPath)
+	}
+
+	logBatchOutcome(results, resultsPath)
+}
+
+// resumeResult decides whether a row from an earlier run is finished. A row with a transaction
+// hash that is not marked sent, whether signed or failed, counts as sent when the node knows its
+// transaction, so a broadcast that reported an error but reached the network is not paid twice.
+func resumeResult(client *ethclient.Client, earlier *batch.Result) bool {
+	if earlier.Done() {
+		return true
+	}
+	if earlier.TxHash == "" {
+		return false
+	}
+
+	_, _, err := client.TransactionByHash(context.Background(), common.HexToHash(earlier.TxHash))
+	if err == nil {
+		earlier.Status = batch.StatusSent
+		return true
+	}
+	if !errors.Is(err, ethereum.NotFound) {
+		logger.Error.Fatalf("Line %d: failed to look up transaction %s: %v", earlier.Line, earlier.TxHash, err)
+	}
+	return false
+}
+
+func newPayoutInput(from common.Address, to string, p payout, chainID *big.Int, txNonce uint64, fees *ethereum_client.GasFees, ethPrice float64) *asset.TransferInput {
+	return &asset.TransferInput{
+		From:       from.Hex(),
+		To:         to,
+		Amount:     p.amount,
+		AssetPrice: p.price,
+		EthPrice:   ethPrice,
+		ChainID:    chainID,
+		Nonce:      txNonce,
+		GasLimit:   21000,
+		GasPrice:   fees.GasPrice,
+		GasTipCap:  fees.GasTipCap,
+		GasFeeCap:  fees.GasFeeCap,
+	}
+}
+
+// errBroadcastUnknown marks a broadcast that failed without the node refusing the transaction,
+// so it may still reach the network
+var errBroadcastUnknown = errors.New("transaction may have been broadcast")
+
+// sendPayout simulates and signs the payout with its earlier or the next nonce, checkpoints it as
+// signed and broadcasts it.
+// The nonce is released again when the payout never reaches the network. When the broadcast
+// outcome is unknown the nonce stays recorded for the signed transaction and the error wraps
+// errBroadcastUnknown.
+func sendPayout(cfg config.Config, client *ethclient.Client, chainID *big.Int, nonces *nonce.Manager, from common.Address, privateKey *ecdsa.PrivateKey,
+	p payout, result *batch.Result, fees *ethereum_client.GasFees, ethPrice float64, checkpoint func()) (*types.Transaction, error) {
+	txNonce := p.nonce
+	if p.reuse {
+		if err := nonces.Assign(from, txNonce); err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		if txNonce, err = nonces.Next(context.Background(), from); err != nil {
+			return nil, err
+		}
+	}
+
+	tx, err := p.asset.CreateTransferTransaction(client, newPayoutInput(from, result.Address, p, chainID, txNonce, fees, ethPrice))
+	if err != nil {
+		releaseNonce(nonces, from, txNonce)
+		return nil, err
+	}
+	if err := simulate.Transaction(context.Background(), client, tx, from, p.asset.ABI()); err != nil {
+		releaseNonce(nonces, from, txNonce)
+		return nil, err
+	}
+	signedTx, err := transaction.SignTransaction(tx, chainID, privateKey)
+	if err != nil {
+		releaseNonce(nonces, from, txNonce)
+		return nil, err
+	}
+
+	result.Nonce = strconv.FormatUint(txNonce, 10)
+	result.TxHash = signedTx.Hash().Hex()
+	result.Status = batch.StatusSigned
+	checkpoint()
+
+	logger.Info.Printf("Line %d: sending %s %s to %s\n", result.Line, asset.FormatUnits(p.amount, p.asset.Decimals()), p.asset.Symbol(), result.Address)
+	if err := transaction.BroadcastTransaction(client, signedTx, cfg.EthereumExplorerUrl); err != nil {
+		if transaction.Rejected(err) {
+			releaseNonce(nonces, from, txNonce)
+			return nil, err
+		}
+		if recordErr := nonces.Record(from, signedTx); recordErr != nil {
+			logger.Error.Printf("Failed to record nonce %d: %v", txNonce, recordErr)
+		}
+		return signedTx, fmt.Errorf("%w: %v", errBroadcastUnknown, err)
+	}
+	return signedTx, nil
+}
+
+// confirmBatch waits for the payouts sent in this run and records whether they succeeded
+func confirmBatch(cfg config.Config, client *ethclient.Client, results []batch.Result, sent []int, resultsPath string) {
+	logger.Info.Printf("Waiting for %d confirmation(s) of %d payout(s)...\n", cfg.Confirmations, len(sent))
+	for _, index := range sent {
+		result := &results[index]
+		tx, _, err := client.TransactionByHash(context.Background(), common.HexToHash(result.TxHash))
+		if err != nil {
+			logger.Error.Printf("Line %d: failed to look up transaction %s: %v", result.Line, result.TxHash, err)
+			continue
+		}
+
+		receipt, err := transaction.WaitForReceipt(context.Background(), client, tx, transaction.WaitOptions{
+			Confirmations: cfg.Confirmations,
+			Subscribe:     true,
+		})
+		if err != nil {
+			result.Error = err.Error()
+			logger.Error.Printf("Line %d: %v", result.Line, err)
+		} else if receipt.Status == transaction.StatusSuccessful {
+			result.Status = batch.StatusConfirmed
+		} else {
+			result.Status = batch.StatusReverted
+		}
+		writeResults(resultsPath, results)
+	}
+}
+
+func writeResults(path string, results []batch.Result) {
+	if err := batch.WriteResults(path, results); err != nil {
+		logger.Error.Fatalf("Failed to save results to %s: %v", path, err)
+	}
+}
+
+func logBatchSummary(payouts []payout, totals map[string]*big.Int, totalFee *big.Int, prices map[string]float64, currency string, done int) {
+	decimals := make(map[string]uint8)
+	for _, p := range payouts {
+		decimals[strings.ToUpper(p.asset.Symbol())] = p.asset.Decimals()
+	}
+
+	symbols := make([]string, 0, len(totals))
+	for symbol := range totals {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+
+	logger.Info.Printf("Payouts to send: %d (already sent: %d)\n", len(payouts), done)
+	for _, symbol := range symbols {
+		logger.Info.Printf("Total %s: %s\n", symbol, formatValue(totals[symbol], decimals[symbol], symbol, prices[symbol], currency))
+	}
+	logger.Info.Printf("Maximum Total Fee: %s\n", formatValue(totalFee, asset.EtherDecimals, "ETH", prices["ETH"], currency))
+}
+
+// formatValue renders an amount in its own unit and in fiat, e.g. "1.5 ETH (3000.00 EUR)"
+func formatValue(value *big.Int, decimals uint8, symbol string, price float64, currency string) string {
+	text := asset.FormatUnits(value, decimals) + " " + symbol
+	if fiat, err := asset.UnitsToFiat(value, price, decimals); err == nil {
+		text += " (" + asset.FormatFiat(fiat, currency, 2) + ")"
+	}
+	return text
+}
+
+func logBatchOutcome(results []batch.Result, resultsPath string) {
+	counts := make(map[string]int)
+	for _, result := range results {
+		status := result.Status
+		if status == "" {
+			status = "not sent"
+		}
+		counts[status]++
+	}
+
+	statuses := make([]string, 0, len(counts))
+	for status, count := range counts {
+		statuses = append(statuses, status+": "+strconv.Itoa(count))
+	}
+	sort.Strings(statuses)
+
+	logger.Info.Printf("Batch finished (%s). Results written to %s\n", strings.Join(statuses, ", "), resultsPath)
+	if counts[batch.StatusFailed]+counts[batch.StatusSigned] > 0 {
+		logger.Info.Println("Run the batch again with the same file to retry the payouts that were not sent.")
+	}
+}
@@ -1,9 +1,21 @@
+// transfer/main.go
+
 package main
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"errors"
+	"flag"
+	"fmt"
 	"math/big"
+	"os"
+	"strings"
+	"time"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
 
 	"go-ethereum-wallet/keygen"
@@ -11,110 +23,284 @@ import (
 	"go-ethereum-wallet/transfer/config"
 	"go-ethereum-wallet/transfer/ethereum_client"
 	"go-ethereum-wallet/transfer/logger"
+	"go-ethereum-wallet/transfer/nonce"
+	"go-ethereum-wallet/transfer/oracle"
+	"go-ethereum-wallet/transfer/registry"
 	"go-ethereum-wallet/transfer/transaction"
 	"go-ethereum-wallet/transfer/userinput"
 )
 
 func main() {
-	var accountName, receiverAddress, assetChoice string
-	var amountInDollars float64
-
-	// Choose the desired configuration
-	cfg := config.SepoliaTestnet
+	cfg, err := config.Load(os.Args[1:], os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		logger.Error.Fatalf("Failed to load configuration: %v", err)
+	}
+	logger.Info.Printf("Network: %s (%s)\n", cfg.Name, cfg.PublicNodeUrl)
 
-	usdtAsset, err := asset.NewUsdt(cfg.UsdtContractAddress)
+	client, err := ethclient.Dial(cfg.PublicNodeUrl)
 	if err != nil {
-		logger.Error.Fatalf("Failed to create Usdt asset: %v", err)
+		logger.Error.Fatalf("Failed to connect to the Ethereum client: %v", err)
 	}
 
-	var assets = map[string]asset.Asset{
-		"1": &asset.Ether{},
-		"2": usdtAsset,
+	chainID, err := client.ChainID(context.Background())
+	if err != nil {
+		logger.Error.Fatalf("Failed to get chain ID: %v", err)
+	}
+	if cfg.ChainID != 0 && chainID.Uint64() != cfg.ChainID {
+		logger.Error.Fatalf("Profile %s expects chain %d, but the node at %s is on chain %d", cfg.Name, cfg.ChainID, cfg.PublicNodeUrl, chainID.Uint64())
 	}
 
-	assetChoice = userinput.SelectAsset(assets)
-	currentAsset, exists := assets[assetChoice]
-	if !exists {
-		logger.Error.Fatalf("Invalid asset choice")
+	fmt.Println("Menu:")
+	fmt.Println("1. Send transfer")
+	fmt.Println("2. Speed up pending transaction")
+	fmt.Println("3. Cancel pending transaction")
+	fmt.Println("4. Send batch payouts from CSV")
+	fmt.Println("5. Build unsigned transaction for offline signing")
+	fmt.Println("6. Broadcast transaction signed offline")
+	fmt.Print("Enter your choice: ")
+
+	var choice int
+	fmt.Scanln(&choice)
+
+	switch choice {
+	case 1:
+		sendTransfer(cfg, client, chainID)
+	case 2:
+		speedUpTransaction(cfg, client)
+	case 3:
+		cancelTransaction(cfg, client)
+	case 4:
+		sendBatch(cfg, client, chainID)
+	case 5:
+		buildUnsignedTransaction(cfg, client, chainID)
+	case 6:
+		broadcastSignedTransaction(cfg, client, chainID)
+	default:
+		logger.Error.Fatalf("Invalid choice")
 	}
+}
 
-	accountName = userinput.GetAccountName()
-	privateKeyHex, err := keygen.RetrievePrivateKeyHex(accountName)
+// loadAssets reads the network's token registry and builds the assets it offers
+func loadAssets(cfg config.Config, client *ethclient.Client, chainID *big.Int) (*registry.Registry, map[string]asset.Asset) {
+	tokenRegistry, err := registry.Load(cfg.TokenRegistryPath)
 	if err != nil {
-		logger.Error.Fatalf("Failed to retrieve private key: %v", err)
+		logger.Error.Fatalf("Failed to load token registry: %v", err)
+	}
+	if tokenRegistry.ChainID != 0 && tokenRegistry.ChainID != chainID.Uint64() {
+		logger.Error.Fatalf("Token registry %s is for chain %d, but the node is on chain %d", cfg.TokenRegistryPath, tokenRegistry.ChainID, chainID.Uint64())
 	}
 
-	receiverAddress = userinput.GetReceiverAddress()
-	ethPrice, err := ethereum_client.GetETHUSDPrice()
+	assets, err := tokenRegistry.Assets(client)
 	if err != nil {
-		logger.Error.Fatalf("Failed to get ETH price: %v", err)
+		logger.Error.Fatalf("Failed to build assets: %v", err)
 	}
-	logger.Info.Printf("Current ETH/USD price: $%.2f\n", ethPrice)
+	return tokenRegistry, assets
+}
 
-	amountInDollars = userinput.GetTransferAmount()
-	if amountInDollars <= 0 {
-		logger.Error.Fatalf("Invalid amount")
+// newPriceOracle prices ETH with cfg.PriceOracle: the median of Coinbase and Chainlink, or
+// either alone. Every token is priced through its registry price source, and Chainlink answers
+// older than cfg.MaxPriceAge are rejected for tokens as for ETH.
+func newPriceOracle(cfg config.Config, client *ethclient.Client, tokenRegistry *registry.Registry) oracle.PriceOracle {
+	coinbase := oracle.NewCoinbaseOracle(10 * time.Second)
+	var ethOracle oracle.PriceOracle = coinbase
+	if cfg.PriceOracle != config.PriceOracleCoinbase {
+		chainlink, err := oracle.NewChainlinkOracle(client, map[string]string{oracle.Pair("ETH", "USD"): cfg.ChainlinkETHUSDFeed})
+		if err != nil {
+			logger.Error.Fatalf("Failed to create Chainlink oracle: %v", err)
+		}
+		sources := []oracle.PriceOracle{chainlink}
+		if cfg.PriceOracle == config.PriceOracleMedian {
+			sources = []oracle.PriceOracle{coinbase, chainlink}
+		}
+		ethOracle = &oracle.MedianOracle{
+			Sources:      sources,
+			MinSources:   1,
+			MaxAge:       cfg.MaxPriceAge,
+			MaxDeviation: cfg.MaxPriceDeviation,
+		}
 	}
-
-	client, err := ethclient.Dial(cfg.PublicNodeUrl)
+	registryOracle, err := tokenRegistry.PriceOracle(client, ethOracle, coinbase, cfg.MaxPriceAge)
 	if err != nil {
-		logger.Error.Fatalf("Failed to connect to the Ethereum client: %v", err)
+		logger.Error.Fatalf("Failed to create price oracle: %v", err)
 	}
+	// Every source quotes in USD; other currencies are converted at the Coinbase rate
+	return &oracle.CrossOracle{Oracle: registryOracle, Rates: coinbase, Via: "USD"}
+}
 
-	fromAddress, privateKey, err := ethereum_client.GetAddressFromPrivateKey(privateKeyHex)
+// quotePrice looks up and logs the price of one whole unit of symbol in currency
+func quotePrice(priceOracle oracle.PriceOracle, symbol, currency string) float64 {
+	quote, err := priceOracle.Price(context.Background(), symbol, currency)
 	if err != nil {
-		logger.Error.Fatalf("Failed to get address from private key: %v", err)
+		logger.Error.Fatalf("Failed to get %s price: %v", symbol, err)
 	}
+	logger.Info.Printf("Current %s price: %.6f %s (%s)\n", oracle.Pair(symbol, currency), quote.Value, currency, quote.Source)
+	return quote.Value
+}
+
+// unlockAccount asks for an account, and an address index for HD accounts, and decrypts its key
+func unlockAccount() (*ecdsa.PrivateKey, common.Address) {
+	ks := keygen.NewKeystore(keygen.AccountPath, keygen.TerminalPasswordProvider{})
 
-	nonce, err := client.PendingNonceAt(context.Background(), fromAddress)
+	accountName := userinput.GetAccountName()
+	var privateKey *ecdsa.PrivateKey
+	var err error
+	if ks.IsHDAccount(accountName) {
+		privateKey, err = ks.DerivedPrivateKey(accountName, userinput.GetAddressIndex())
+	} else {
+		privateKey, err = ks.PrivateKey(accountName)
+	}
 	if err != nil {
-		logger.Error.Fatalf("Failed to get nonce: %v", err)
+		logger.Error.Fatalf("Failed to retrieve private key: %v", err)
 	}
-	logger.Info.Printf("Nonce: %d\n", nonce)
+	return privateKey, crypto.PubkeyToAddress(privateKey.PublicKey)
+}
 
-	increasedGasPrice, err := ethereum_client.CalculateGasPrice(client, ethPrice)
-	if err != nil {
-		logger.Error.Fatalf("Failed to calculate gas price: %v", err)
+// selectGasFees uses the configured gas strategy, or asks for one when none is configured,
+// and returns the fees it currently suggests
+func selectGasFees(cfg config.Config, client *ethclient.Client) (ethereum_client.GasStrategy, *ethereum_client.GasFees) {
+	var gasStrategies = map[string]ethereum_client.GasStrategy{
+		"1": &ethereum_client.SuggestedStrategy{},
+		"2": &ethereum_client.MultiplierStrategy{Factor: 1.25},
+		"3": &ethereum_client.FeeHistoryStrategy{Blocks: 20, Percentile: 50},
+		"4": &ethereum_client.FixedCapStrategy{MaxGwei: cfg.MaxGasPriceGwei},
+	}
+	var strategyNames = map[string]string{
+		config.GasStrategySuggested:  "1",
+		config.GasStrategyMultiplier: "2",
+		config.GasStrategyFeeHistory: "3",
+		config.GasStrategyFixedCap:   "4",
 	}
 
-	ethereum_client.DisplayGasPrices(client, ethPrice, increasedGasPrice)
+	choice, configured := strategyNames[cfg.GasStrategy]
+	if !configured {
+		choice = userinput.SelectGasStrategy(gasStrategies)
+	}
+	gasStrategy, exists := gasStrategies[choice]
+	if !exists {
+		logger.Error.Fatalf("Invalid gas strategy choice")
+	}
 
-	gasLimit := uint64(21000)
-	transactionFeeUSD := ethereum_client.CalculateTransactionFee(increasedGasPrice, gasLimit, ethPrice)
-	logger.Info.Printf("Transaction Fee: $%.6f\n", transactionFeeUSD)
+	fees, err := gasStrategy.Fees(context.Background(), client)
+	if err != nil {
+		logger.Error.Fatalf("Failed to calculate gas fees: %v", err)
+	}
+	return gasStrategy, fees
+}
 
-	balance, err := client.BalanceAt(context.Background(), fromAddress, nil)
+// fillNonceGaps reconciles account's nonces before a new one is reserved. A dropped earlier nonce
+// holds back everything after it, so each gap is filled first by rebroadcasting its transaction or
+// by cancelling the nonce. The new transfer never takes a gap's nonce: the original could still be
+// mined and would then be replaced. privateKey is nil for offline builds, which cannot cancel.
+func fillNonceGaps(cfg config.Config, client *ethclient.Client, chainID *big.Int, nonces *nonce.Manager, account common.Address, privateKey *ecdsa.PrivateKey) {
+	reconciliation, err := nonces.Reconcile(context.Background(), account)
 	if err != nil {
-		logger.Error.Fatalf("Failed to get balance: %v", err)
+		logger.Error.Fatalf("Failed to reconcile nonces: %v", err)
 	}
-	logger.Info.Printf("Sender's balance: %s wei\n", balance.String())
 
-	requiredGasFee := new(big.Int).Mul(big.NewInt(int64(gasLimit)), increasedGasPrice)
-	if balance.Cmp(requiredGasFee) < 0 {
-		logger.Error.Fatalf("Insufficient balance to cover transaction fee: required %s wei, but only %s wei available", requiredGasFee.String(), balance.String())
+	if len(reconciliation.Missing) > 0 {
+		logger.Info.Printf("The node does not know the transactions with nonce %v yet; they are treated as pending\n", reconciliation.Missing)
 	}
+	for _, entry := range reconciliation.Pending {
+		for _, gap := range reconciliation.Gaps {
+			if entry.Nonce == gap {
+				fillNonceGap(cfg, client, chainID, nonces, account, privateKey, entry)
+			}
+		}
+	}
+}
 
-	if !userinput.ConfirmTransaction() {
-		logger.Info.Println("Transaction cancelled.")
+// fillNonceGap rebroadcasts the transaction kept for a dropped nonce. When there is none, or the
+// node refuses it, it offers to cancel the nonce with a zero-value transfer to the account itself.
+func fillNonceGap(cfg config.Config, client *ethclient.Client, chainID *big.Int, nonces *nonce.Manager, account common.Address, privateKey *ecdsa.PrivateKey, entry nonce.Entry) {
+	original, err := entry.Transaction()
+	if err != nil {
+		logger.Error.Printf("Cannot rebroadcast: %v", err)
+	}
+	if original != nil {
+		err := client.SendTransaction(context.Background(), original)
+		if err == nil || strings.Contains(err.Error(), "already known") || strings.Contains(err.Error(), "nonce too low") {
+			logger.Info.Printf("Rebroadcast transaction %s with nonce %d\n", original.Hash().Hex(), entry.Nonce)
+			return
+		}
+		logger.Error.Printf("Failed to rebroadcast transaction %s with nonce %d: %v", original.Hash().Hex(), entry.Nonce, err)
+	}
+
+	if privateKey == nil {
+		logger.Error.Fatalf("Nonce %d of %s was dropped; start a transfer from the unlocked account to cancel it first", entry.Nonce, account.Hex())
+	}
+	logger.Info.Printf("Nonce %d was dropped: later transactions are stuck until it is filled\n", entry.Nonce)
+	if !userinput.ConfirmCancelNonce(entry.Nonce) {
+		logger.Info.Printf("Nonce %d left unfilled; this transfer will wait behind it\n", entry.Nonce)
 		return
 	}
 
-	input := &asset.TransferInput{
-		From:     fromAddress.Hex(),
-		To:       receiverAddress,
-		Amount:   amountInDollars,
-		EthPrice: ethPrice,
-		Nonce:    nonce,
-		GasLimit: gasLimit,
-		GasPrice: increasedGasPrice,
+	fees, err := (&ethereum_client.SuggestedStrategy{}).Fees(context.Background(), client)
+	if err != nil {
+		logger.Error.Fatalf("Failed to calculate gas fees: %v", err)
+	}
+	cancel := transaction.CancelNonce(chainID, entry.Nonce, account, fees)
+	if original != nil {
+		if cancel, err = transaction.Cancel(original, account, fees); err != nil {
+			logger.Error.Fatalf("Failed to build cancellation: %v", err)
+		}
+	}
+
+	signedTx, err := transaction.SendTransaction(client, cancel, privateKey, cfg.EthereumExplorerUrl)
+	if err != nil {
+		logger.Error.Fatalf("Failed to cancel nonce %d: %v", entry.Nonce, err)
+	}
+	if err := nonces.Record(account, signedTx); err != nil {
+		logger.Error.Printf("Failed to record nonce %d: %v", entry.Nonce, err)
+	}
+}
+
+// releaseNonce returns an unused nonce to the manager
+// maxTransactionFee is the most tx can cost in fees: its gas limit at its fee cap, which is the
+// gas price of a legacy transaction
+func maxTransactionFee(tx *types.Transaction) *big.Int {
+	return new(big.Int).Mul(tx.GasFeeCap(), new(big.Int).SetUint64(tx.Gas()))
+}
+
+func releaseNonce(nonces *nonce.Manager, account common.Address, txNonce uint64) {
+	if err := nonces.Release(account, txNonce); err != nil {
+		logger.Error.Printf("Failed to release nonce %d: %v", txNonce, err)
 	}
+}
 
-	tx, err := currentAsset.CreateTransferTransaction(client, input)
+// waitForConfirmations waits for cfg.Confirmations blocks and logs the receipt.
+// A zero ethPrice logs the fee in ETH only.
+func waitForConfirmations(cfg config.Config, client *ethclient.Client, signedTx *types.Transaction, ethPrice float64, currency string) {
+	if cfg.Confirmations == 0 {
+		return
+	}
+	logger.Info.Printf("Waiting for %d confirmation(s)...\n", cfg.Confirmations)
+	result, err := transaction.WaitForReceipt(context.Background(), client, signedTx, transaction.WaitOptions{
+		Confirmations: cfg.Confirmations,
+		Subscribe:     true,
+	})
 	if err != nil {
-		logger.Error.Fatalf("Failed to create transaction: %v", err)
+		logger.Error.Fatalf("Failed to confirm transaction: %v", err)
 	}
+	logReceipt(result, ethPrice, currency)
+	if result.Status != transaction.StatusSuccessful {
+		logger.Error.Fatalf("Transaction %s was reverted", result.TxHash.Hex())
+	}
+}
 
-	if err := transaction.SendTransaction(client, tx, privateKey, cfg.EthereumExplorerUrl); err != nil {
-		logger.Error.Fatalf("Transaction sending failed: %v", err)
+func logReceipt(result *transaction.Result, ethPrice float64, currency string) {
+	logger.Info.Printf("Status: %s\n", result.Status)
+	logger.Info.Printf("Block: %d (%d confirmation(s))\n", result.BlockNumber, result.Confirmations)
+	logger.Info.Printf("Gas Used: %d\n", result.GasUsed)
+	logger.Info.Printf("Effective Gas Price: %s Gwei\n", asset.FormatUnits(result.EffectiveGasPrice, 9))
+
+	fee := result.Fee()
+	feeValue, err := asset.UnitsToFiat(fee, ethPrice, asset.EtherDecimals)
+	if err != nil {
+		logger.Info.Printf("Fee Paid: %s ETH\n", asset.FormatUnits(fee, asset.EtherDecimals))
+		return
 	}
+	logger.Info.Printf("Fee Paid: %s ETH (%s)\n", asset.FormatUnits(fee, asset.EtherDecimals), asset.FormatFiat(feeValue, currency, 6))
 }
@@ -0,0 +1,105 @@
+// transfer/offline.go
+
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"go-ethereum-wallet/transfer/asset"
+	"go-ethereum-wallet/transfer/config"
+	"go-ethereum-wallet/transfer/logger"
+	"go-ethereum-wallet/transfer/nonce"
+	"go-ethereum-wallet/transfer/offline"
+	"go-ethereum-wallet/transfer/preflight"
+	"go-ethereum-wallet/transfer/simulate"
+	"go-ethereum-wallet/transfer/transaction"
+	"go-ethereum-wallet/transfer/userinput"
+)
+
+// buildUnsignedTransaction plans a transfer without any key and writes it to a file
+// for signing on an offline machine with the keygen tool
+func buildUnsignedTransaction(cfg config.Config, client *ethclient.Client, chainID *big.Int) {
+	plan := planTransfer(cfg, client, chainID, func() (*ecdsa.PrivateKey, common.Address) {
+		sender := userinput.GetSenderAddress()
+		if !common.IsHexAddress(sender) {
+			logger.Error.Fatalf("Invalid sender address %q", sender)
+		}
+		return nil, common.HexToAddress(sender)
+	})
+	if plan == nil {
+		return
+	}
+
+	unsigned, err := offline.NewUnsignedTransaction(plan.tx, chainID, plan.from, offline.Summary{
+		Asset:     plan.asset.Symbol(),
+		Amount:    asset.FormatUnits(plan.input.Amount, plan.asset.Decimals()),
+		Recipient: plan.input.To,
+	})
+	if err != nil {
+		releaseNonce(plan.nonces, plan.from, plan.tx.Nonce())
+		logger.Error.Fatalf("Failed to describe transaction: %v", err)
+	}
+
+	path := userinput.GetTransactionFilePath("unsigned transaction to write")
+	if err := offline.WriteFile(path, unsigned); err != nil {
+		releaseNonce(plan.nonces, plan.from, plan.tx.Nonce())
+		logger.Error.Fatalf("Failed to save unsigned transaction: %v", err)
+	}
+	logger.Info.Printf("Unsigned transaction with nonce %d written to %s\n", unsigned.Nonce, path)
+	logger.Info.Printf("Sign it offline with the keygen tool, then broadcast %s\n", offline.SignedPath(path))
+}
+
+// broadcastSignedTransaction sends a transaction signed offline and waits for it like a normal transfer
+func broadcastSignedTransaction(cfg config.Config, client *ethclient.Client, chainID *big.Int) {
+	path := userinput.GetTransactionFilePath("signed transaction to broadcast")
+	signed, err := offline.ReadSigned(path)
+	if err != nil {
+		logger.Error.Fatalf("Failed to read signed transaction: %v", err)
+	}
+	if signed.ChainID != chainID.Uint64() {
+		logger.Error.Fatalf("Transaction is for chain %d, but the node is on chain %d", signed.ChainID, chainID.Uint64())
+	}
+
+	signedTx, err := signed.Transaction()
+	if err != nil {
+		logger.Error.Fatalf("Invalid signed transaction: %v", err)
+	}
+	from := common.HexToAddress(signed.From)
+
+	mined, err := client.NonceAt(context.Background(), from, nil)
+	if err != nil {
+		logger.Error.Fatalf("Failed to get nonce: %v", err)
+	}
+	if signedTx.Nonce() < mined {
+		logger.Error.Fatalf("Nonce %d of %s is already used on chain; the transaction can no longer be mined", signedTx.Nonce(), from.Hex())
+	}
+
+	requirements := preflight.ForTransaction(&asset.Ether{}, signedTx, signedTx.Value(), 0, 0)
+	if err := preflight.Check(context.Background(), client, from, cfg.Currency, requirements...); err != nil {
+		logger.Error.Fatalf("Transaction not sent: %v", err)
+	}
+	if err := simulate.Transaction(context.Background(), client, signedTx, from, nil); err != nil {
+		logger.Error.Fatalf("Transaction not sent, simulation failed: %v", err)
+	}
+
+	logger.Info.Printf("Broadcasting %s %s to %s from %s (nonce %d)\n", signed.Summary.Amount, signed.Summary.Asset, signed.Summary.Recipient, signed.From, signed.Nonce)
+	if !userinput.ConfirmTransaction("", maxTransactionFee(signedTx)) {
+		logger.Info.Println("Transaction cancelled.")
+		return
+	}
+
+	if err := transaction.BroadcastTransaction(client, signedTx, cfg.EthereumExplorerUrl); err != nil {
+		logger.Error.Fatalf("Transaction sending failed: %v", err)
+	}
+	nonces := nonce.NewManager(cfg.NonceStorePath, client, chainID)
+	if err := nonces.Record(from, signedTx); err != nil {
+		logger.Error.Printf("Failed to record nonce %d: %v", signedTx.Nonce(), err)
+	}
+
+	waitForConfirmations(cfg, client, signedTx, 0, cfg.Currency)
+}
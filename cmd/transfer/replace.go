@@ -0,0 +1,93 @@
+// transfer/replace.go
+
+package main
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"go-ethereum-wallet/transfer/asset"
+	"go-ethereum-wallet/transfer/config"
+	"go-ethereum-wallet/transfer/ethereum_client"
+	"go-ethereum-wallet/transfer/logger"
+	"go-ethereum-wallet/transfer/nonce"
+	"go-ethereum-wallet/transfer/transaction"
+	"go-ethereum-wallet/transfer/userinput"
+)
+
+func speedUpTransaction(cfg config.Config, client *ethclient.Client) {
+	replaceTransaction(cfg, client, "Speed up", func(tx *types.Transaction, sender common.Address, fees *ethereum_client.GasFees) (*types.Transaction, error) {
+		return transaction.SpeedUp(tx, fees)
+	})
+}
+
+func cancelTransaction(cfg config.Config, client *ethclient.Client) {
+	replaceTransaction(cfg, client, "Cancel", transaction.Cancel)
+}
+
+// replaceTransaction looks up a pending transaction of the unlocked account and sends the
+// replacement built by build at the same nonce
+func replaceTransaction(cfg config.Config, client *ethclient.Client, action string,
+	build func(*types.Transaction, common.Address, *ethereum_client.GasFees) (*types.Transaction, error)) {
+	privateKey, fromAddress := unlockAccount()
+
+	txHash := userinput.GetTransactionHash()
+	if len(common.FromHex(txHash)) != common.HashLength {
+		logger.Error.Fatalf("Invalid transaction hash %q", txHash)
+	}
+
+	pendingTx, isPending, err := client.TransactionByHash(context.Background(), common.HexToHash(txHash))
+	if err != nil {
+		logger.Error.Fatalf("Failed to find transaction %s: %v", txHash, err)
+	}
+	if !isPending {
+		logger.Error.Fatalf("Transaction %s is already mined", txHash)
+	}
+
+	sender, err := types.Sender(types.LatestSignerForChainID(pendingTx.ChainId()), pendingTx)
+	if err != nil {
+		logger.Error.Fatalf("Failed to recover transaction sender: %v", err)
+	}
+	if sender != fromAddress {
+		logger.Error.Fatalf("Transaction %s was sent by %s, not by the unlocked account %s", txHash, sender.Hex(), fromAddress.Hex())
+	}
+
+	gasStrategy, fees := selectGasFees(cfg, client)
+	replacement, err := build(pendingTx, fromAddress, fees)
+	if err != nil {
+		logger.Error.Fatalf("Failed to build replacement: %v", err)
+	}
+
+	logger.Info.Printf("%s transaction %s at nonce %d\n", action, txHash, pendingTx.Nonce())
+	logFeeChange("Max Priority Fee", pendingTx.GasTipCap(), replacement.GasTipCap())
+	logFeeChange("Max Fee", pendingTx.GasFeeCap(), replacement.GasFeeCap())
+	maxFee := maxTransactionFee(replacement)
+	logger.Info.Printf("Maximum Transaction Fee: %s ETH\n", asset.FormatUnits(maxFee, asset.EtherDecimals))
+
+	if !userinput.ConfirmTransaction(gasStrategy.Name(), maxFee) {
+		logger.Info.Println("Transaction cancelled.")
+		return
+	}
+
+	signedTx, err := transaction.SendTransaction(client, replacement, privateKey, cfg.EthereumExplorerUrl)
+	if err != nil {
+		logger.Error.Fatalf("Transaction sending failed: %v", err)
+	}
+
+	nonces := nonce.NewManager(cfg.NonceStorePath, client, pendingTx.ChainId())
+	if err := nonces.Record(fromAddress, signedTx); err != nil {
+		logger.Error.Printf("Failed to record nonce %d: %v", signedTx.Nonce(), err)
+	}
+
+	waitForConfirmations(cfg, client, signedTx, 0, cfg.Currency)
+}
+
+// logFeeChange logs an old and new per-gas price in Gwei. For legacy transactions both
+// GasTipCap and GasFeeCap report the gas price.
+func logFeeChange(label string, original, replacement *big.Int) {
+	logger.Info.Printf("%s: %s Gwei -> %s Gwei\n", label, asset.FormatUnits(original, 9), asset.FormatUnits(replacement, 9))
+}
@@ -0,0 +1,182 @@
+// transfer/transfer.go
+
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"go-ethereum-wallet/transfer/asset"
+	"go-ethereum-wallet/transfer/config"
+	"go-ethereum-wallet/transfer/ethereum_client"
+	"go-ethereum-wallet/transfer/logger"
+	"go-ethereum-wallet/transfer/nonce"
+	"go-ethereum-wallet/transfer/preflight"
+	"go-ethereum-wallet/transfer/simulate"
+	"go-ethereum-wallet/transfer/transaction"
+	"go-ethereum-wallet/transfer/userinput"
+)
+
+// errTransferCancelled is returned when the user declines the transfer summary
+var errTransferCancelled = errors.New("transfer cancelled")
+
+// plannedTransfer is a confirmed transfer whose transaction is built but not yet signed.
+// key is nil when the transfer is built for offline signing.
+type plannedTransfer struct {
+	asset    asset.Asset
+	input    *asset.TransferInput
+	tx       *types.Transaction
+	from     common.Address
+	key      *ecdsa.PrivateKey
+	nonces   *nonce.Manager
+	ethPrice float64
+	currency string
+}
+
+func sendTransfer(cfg config.Config, client *ethclient.Client, chainID *big.Int) {
+	plan := planTransfer(cfg, client, chainID, unlockAccount)
+	if plan == nil {
+		return
+	}
+
+	signedTx, err := transaction.SendTransaction(client, plan.tx, plan.key, cfg.EthereumExplorerUrl)
+	if err != nil {
+		releaseNonce(plan.nonces, plan.from, plan.tx.Nonce())
+		logger.Error.Fatalf("Transaction sending failed: %v", err)
+	}
+	if err := plan.nonces.Record(plan.from, signedTx); err != nil {
+		logger.Error.Printf("Failed to record nonce %d: %v", signedTx.Nonce(), err)
+	}
+
+	waitForConfirmations(cfg, client, signedTx, plan.ethPrice, plan.currency)
+}
+
+// planTransfer asks for the asset, sender, recipient, amount and gas, builds the transaction,
+// checks the sender can pay for it, simulates it and shows the totals for the user to confirm. It returns nil when the user cancels.
+func planTransfer(cfg config.Config, client *ethclient.Client, chainID *big.Int, selectSender func() (*ecdsa.PrivateKey, common.Address)) *plannedTransfer {
+	var receiverAddress, assetChoice string
+
+	tokenRegistry, assets := loadAssets(cfg, client, chainID)
+
+	assetChoice = userinput.SelectAsset(assets)
+	currentAsset, exists := assets[assetChoice]
+	if !exists {
+		logger.Error.Fatalf("Invalid asset choice")
+	}
+
+	privateKey, fromAddress := selectSender()
+
+	receiverAddress = userinput.GetReceiverAddress()
+	priceOracle := newPriceOracle(cfg, client, tokenRegistry)
+	currency := cfg.Currency
+
+	ethPrice := quotePrice(priceOracle, "ETH", currency)
+	assetPrice := ethPrice
+	if currentAsset.Symbol() != "ETH" {
+		assetPrice = quotePrice(priceOracle, currentAsset.Symbol(), currency)
+	}
+
+	amountText, unit := userinput.GetTransferAmount(asset.UnitNames(currentAsset, currency))
+	amount, err := asset.ParseAmount(amountText, unit, currentAsset, assetPrice, currency)
+	if err != nil {
+		logger.Error.Fatalf("Invalid amount: %v", err)
+	}
+	if amount.Sign() <= 0 {
+		logger.Error.Fatalf("Invalid amount")
+	}
+
+	gasStrategy, fees := selectGasFees(cfg, client)
+
+	ethereum_client.DisplayGasPrices(gasStrategy, fees, ethPrice, currency)
+
+	nonces := nonce.NewManager(cfg.NonceStorePath, client, chainID)
+	fillNonceGaps(cfg, client, chainID, nonces, fromAddress, privateKey)
+
+	// Every step that can stop the transfer runs inside Reserve, which gives the nonce back on failure
+	var input *asset.TransferInput
+	var tx *types.Transaction
+	_, err = nonces.Reserve(context.Background(), fromAddress, func(txNonce uint64) error {
+		logger.Info.Printf("Nonce: %d\n", txNonce)
+
+		gasLimit := uint64(21000)
+		input = &asset.TransferInput{
+			From:       fromAddress.Hex(),
+			To:         receiverAddress,
+			Amount:     amount,
+			AssetPrice: assetPrice,
+			EthPrice:   ethPrice,
+			ChainID:    chainID,
+			Nonce:      txNonce,
+			GasLimit:   gasLimit,
+			GasPrice:   fees.GasPrice,
+			GasTipCap:  fees.GasTipCap,
+			GasFeeCap:  fees.GasFeeCap,
+		}
+
+		// Check the balances before estimating gas, which fails without explaining a shortfall
+		if err := preflight.Check(context.Background(), client, fromAddress, currency, preflight.ForInput(currentAsset, input)...); err != nil {
+			return fmt.Errorf("transaction not sent: %w", err)
+		}
+
+		var err error
+		tx, err = currentAsset.CreateTransferTransaction(client, input)
+		if err != nil {
+			return fmt.Errorf("failed to create transaction: %w", err)
+		}
+		input.GasLimit = tx.Gas()
+
+		feeValue, err := input.FeeValue()
+		if err != nil {
+			return fmt.Errorf("failed to value transaction fee: %w", err)
+		}
+		logger.Info.Printf("Transaction Fee: %s\n", asset.FormatFiat(feeValue, currency, 6))
+
+		if tx.Gas() != gasLimit {
+			// The estimated gas limit raises the maximum fee, so check the ETH balance again
+			requirements := preflight.ForTransaction(currentAsset, tx, amount, assetPrice, ethPrice)
+			if err := preflight.Check(context.Background(), client, fromAddress, currency, requirements...); err != nil {
+				return fmt.Errorf("transaction not sent: %w", err)
+			}
+		}
+		if err := simulate.Transaction(context.Background(), client, tx, fromAddress, currentAsset.ABI()); err != nil {
+			return fmt.Errorf("transaction not sent, simulation failed: %w", err)
+		}
+		logger.Info.Println("Simulation succeeded at the pending block")
+
+		amountValue, err := input.AmountValue(currentAsset.Decimals())
+		if err != nil {
+			return fmt.Errorf("failed to value amount: %w", err)
+		}
+		logger.Info.Printf("Sending %s %s (%s) to %s\n", asset.FormatUnits(amount, currentAsset.Decimals()), currentAsset.Symbol(), asset.FormatFiat(amountValue, currency, 2), receiverAddress)
+
+		if !userinput.ConfirmTransaction(gasStrategy.Name(), maxTransactionFee(tx)) {
+			return errTransferCancelled
+		}
+		return nil
+	})
+	if errors.Is(err, errTransferCancelled) {
+		logger.Info.Println("Transaction cancelled.")
+		return nil
+	}
+	if err != nil {
+		logger.Error.Fatalf("Transfer stopped: %v", err)
+	}
+
+	return &plannedTransfer{
+		asset:    currentAsset,
+		input:    input,
+		tx:       tx,
+		from:     fromAddress,
+		key:      privateKey,
+		nonces:   nonces,
+		ethPrice: ethPrice,
+		currency: currency,
+	}
+}
@@ -4,7 +4,10 @@ go 1.22.2
 
 require (
 	github.com/ethereum/go-ethereum v1.14.5
+	github.com/google/uuid v1.3.0
+	github.com/tyler-smith/go-bip39 v1.1.0
 	golang.org/x/crypto v0.24.0
+	golang.org/x/sys v0.21.0
 )
 
 require (
@@ -18,6 +21,7 @@ require (
 	github.com/deckarep/golang-set/v2 v2.6.0 // indirect
 	github.com/decred/dcrd/dcrec/secp256k1/v4 v4.0.1 // indirect
 	github.com/ethereum/c-kzg-4844 v1.0.0 // indirect
+	github.com/fsnotify/fsnotify v1.6.0 // indirect
 	github.com/go-ole/go-ole v1.3.0 // indirect
 	github.com/gorilla/websocket v1.4.2 // indirect
 	github.com/holiman/uint256 v1.2.4 // indirect
@@ -28,7 +32,6 @@ require (
 	github.com/tklauser/numcpus v0.6.1 // indirect
 	golang.org/x/exp v0.0.0-20231110203233-9a3e6036ecaa // indirect
 	golang.org/x/sync v0.7.0 // indirect
-	golang.org/x/sys v0.21.0 // indirect
 	golang.org/x/term v0.21.0 // indirect
 	rsc.io/tmplfunc v0.0.3 // indirect
 )
@@ -0,0 +1,35 @@
+// internal/filelock/filelock.go
+
+package filelock
+
+import (
+	"os"
+)
+
+// Lock is an exclusive lock on a file shared by every process that locks the same path.
+// The operating system releases it when the process exits, so a crash never leaves it held.
+type Lock struct {
+	file *os.File
+}
+
+// Acquire creates path if needed and blocks until this process holds the exclusive lock on it
+func Acquire(path string) (*Lock, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(file); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &Lock{file: file}, nil
+}
+
+// Release gives the lock up for other processes
+func (l *Lock) Release() error {
+	if err := unlockFile(l.file); err != nil {
+		l.file.Close()
+		return err
+	}
+	return l.file.Close()
+}
@@ -0,0 +1,289 @@
+// keygen/envelope.go
+
+package keygen
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// FormatVersionLegacy is the original headerless layout: salt, then nonce, then AES-GCM ciphertext
+	FormatVersionLegacy = 1
+	// FormatVersionUnauthenticatedHeader is the JSON envelope whose header is not covered by the AES-GCM tag
+	FormatVersionUnauthenticatedHeader = 2
+	// FormatVersion is the current JSON envelope; its header is the AES-GCM additional data
+	FormatVersion = 3
+
+	KDFScrypt       = "scrypt"
+	KDFArgon2id     = "argon2id"
+	CipherAES256GCM = "aes-256-gcm"
+
+	legacySaltSize = 16
+
+	// maxKDFMemory bounds the memory a file's KDF parameters may ask for (2 GiB), so a crafted
+	// header cannot exhaust the machine before the password is even checked
+	maxKDFMemory = 2 << 30
+	// maxKDFWork bounds scrypt's n*r*p, 64 times the default parameters
+	maxKDFWork = 1 << 24
+	// maxArgon2Passes bounds the Argon2id passes
+	maxArgon2Passes = 32
+)
+
+// KDFParams selects the key derivation function and its tuning parameters.
+// N and R tune scrypt, T (passes) and M (memory in KiB) tune Argon2id, and P is the parallelism of both.
+type KDFParams struct {
+	Name   string `json:"-"`
+	N      int    `json:"n,omitempty"`
+	R      int    `json:"r,omitempty"`
+	T      uint32 `json:"t,omitempty"`
+	M      uint32 `json:"m,omitempty"`
+	P      int    `json:"p,omitempty"`
+	KeyLen int    `json:"dklen"`
+	Salt   string `json:"salt"`
+}
+
+// DefaultScryptParams are the scrypt parameters used since the first format version
+var DefaultScryptParams = KDFParams{Name: KDFScrypt, N: 32768, R: 8, P: 1, KeyLen: 32}
+
+// DefaultArgon2idParams follow the RFC 9106 second recommended option (64 MiB, 3 passes)
+var DefaultArgon2idParams = KDFParams{Name: KDFArgon2id, T: 3, M: 64 * 1024, P: 4, KeyLen: 32}
+
+// legacyKDFParams are the parameters hard-coded in FormatVersionLegacy files
+var legacyKDFParams = DefaultScryptParams
+
+// Key sources recorded in KeyMetadata
+const (
+	SourceGenerated = "generated"
+	SourceImported  = "imported"
+	SourceDerived   = "derived"
+)
+
+// KeyMetadata is stored unencrypted next to the ciphertext, so accounts can be listed without a password
+type KeyMetadata struct {
+	Address string     `json:"address,omitempty"`
+	Path    string     `json:"path,omitempty"`
+	Source  string     `json:"source,omitempty"`
+	Created *time.Time `json:"created,omitempty"`
+}
+
+func newKeyMetadata(address common.Address, source string) KeyMetadata {
+	created := time.Now().UTC().Truncate(time.Second)
+	return KeyMetadata{Address: address.Hex(), Source: source, Created: &created}
+}
+
+// Envelope is the versioned, self-describing encrypted key file
+type Envelope struct {
+	Version int `json:"version"`
+	KeyMetadata
+	Cipher     string    `json:"cipher"`
+	KDF        string    `json:"kdf"`
+	KDFParams  KDFParams `json:"kdfparams"`
+	Nonce      string    `json:"nonce"`
+	Ciphertext string    `json:"ciphertext"`
+
+	// legacy holds the raw contents of a FormatVersionLegacy file
+	legacy []byte
+}
+
+// EncryptKeyWithMetadata encrypts the key into an envelope using the given KDF parameters
+func EncryptKeyWithMetadata(key []byte, passphrase string, metadata KeyMetadata, params KDFParams) ([]byte, error) {
+	salt := make([]byte, 16)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return nil, err
+	}
+	params.Salt = hex.EncodeToString(salt)
+
+	dk, err := deriveKey(passphrase, params)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(dk)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	envelope := Envelope{
+		Version:     FormatVersion,
+		KeyMetadata: metadata,
+		Cipher:      CipherAES256GCM,
+		KDF:         params.Name,
+		KDFParams:   params,
+		Nonce:       hex.EncodeToString(nonce),
+	}
+	header, err := envelope.header()
+	if err != nil {
+		return nil, err
+	}
+	envelope.Ciphertext = hex.EncodeToString(gcm.Seal(nil, nonce, key, header))
+	return json.MarshalIndent(envelope, "", "  ")
+}
+
+// header encodes every field but the ciphertext. From FormatVersion on it is passed to AES-GCM as
+// additional data, so a file whose address, path, source, creation time or KDF settings were
+// changed no longer decrypts.
+func (e Envelope) header() ([]byte, error) {
+	e.Ciphertext = ""
+	return json.Marshal(e)
+}
+
+// ParseEnvelope reads the header of an encrypted key file of any format version.
+// Data that starts with "{" is a JSON envelope and must parse as one; anything else is a
+// FormatVersionLegacy file. The one exception is a legacy file whose random salt happens to
+// start with "{": it is not valid UTF-8, which every JSON envelope is.
+func ParseEnvelope(data []byte) (*Envelope, error) {
+	if !bytes.HasPrefix(data, []byte("{")) || !utf8.Valid(data) {
+		if len(data) <= legacySaltSize {
+			return nil, fmt.Errorf("encrypted key too short")
+		}
+		return &Envelope{
+			Version:   FormatVersionLegacy,
+			Cipher:    CipherAES256GCM,
+			KDF:       legacyKDFParams.Name,
+			KDFParams: legacyKDFParams,
+			legacy:    data,
+		}, nil
+	}
+
+	var envelope Envelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, fmt.Errorf("invalid key file: %v", err)
+	}
+	if envelope.Version <= FormatVersionLegacy || envelope.Version > FormatVersion {
+		return nil, fmt.Errorf("unsupported key file version %d", envelope.Version)
+	}
+	envelope.KDFParams.Name = envelope.KDF
+	return &envelope, nil
+}
+
+// ReadEnvelope reads the header of an encrypted key file without decrypting it
+func ReadEnvelope(filePath string) (*Envelope, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return ParseEnvelope(data)
+}
+
+// Decrypt returns the plaintext key stored in the envelope
+func (e *Envelope) Decrypt(passphrase string) ([]byte, error) {
+	if e.Cipher != CipherAES256GCM {
+		return nil, fmt.Errorf("unsupported cipher %q", e.Cipher)
+	}
+
+	var salt, nonce, ciphertext []byte
+	if e.Version == FormatVersionLegacy {
+		salt, ciphertext = e.legacy[:legacySaltSize], e.legacy[legacySaltSize:]
+	} else {
+		var err error
+		if salt, err = hex.DecodeString(e.KDFParams.Salt); err != nil {
+			return nil, fmt.Errorf("invalid salt: %v", err)
+		}
+		if nonce, err = hex.DecodeString(e.Nonce); err != nil {
+			return nil, fmt.Errorf("invalid nonce: %v", err)
+		}
+		if ciphertext, err = hex.DecodeString(e.Ciphertext); err != nil {
+			return nil, fmt.Errorf("invalid ciphertext: %v", err)
+		}
+	}
+
+	params := e.KDFParams
+	params.Salt = hex.EncodeToString(salt)
+	dk, err := deriveKey(passphrase, params)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(dk)
+	if err != nil {
+		return nil, err
+	}
+
+	var additionalData []byte
+	switch {
+	case e.Version == FormatVersionLegacy:
+		nonceSize := gcm.NonceSize()
+		if len(ciphertext) < nonceSize {
+			return nil, fmt.Errorf("ciphertext too short")
+		}
+		nonce, ciphertext = ciphertext[:nonceSize], ciphertext[nonceSize:]
+	case e.Version >= FormatVersion:
+		if additionalData, err = e.header(); err != nil {
+			return nil, err
+		}
+	}
+
+	return gcm.Open(nil, nonce, ciphertext, additionalData)
+}
+
+func deriveKey(passphrase string, params KDFParams) ([]byte, error) {
+	salt, err := hex.DecodeString(params.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid salt: %v", err)
+	}
+	if err := ValidateKDFParams(params); err != nil {
+		return nil, err
+	}
+
+	if params.Name == KDFArgon2id {
+		return argon2.IDKey([]byte(passphrase), salt, params.T, params.M, uint8(params.P), uint32(params.KeyLen)), nil
+	}
+	return scrypt.Key([]byte(passphrase), salt, params.N, params.R, params.P, params.KeyLen)
+}
+
+// ValidateKDFParams rejects parameters the KDF cannot run with, or that need more than
+// maxKDFMemory or take far longer than the defaults
+func ValidateKDFParams(params KDFParams) error {
+	if params.KeyLen != 32 {
+		return fmt.Errorf("%s requires a 32-byte key, got %d", CipherAES256GCM, params.KeyLen)
+	}
+
+	switch params.Name {
+	case KDFScrypt:
+		if params.N <= 1 || params.N&(params.N-1) != 0 || params.R < 1 || params.P < 1 || params.R*params.P >= 1<<30 {
+			return fmt.Errorf("invalid %s parameters n=%d r=%d p=%d", KDFScrypt, params.N, params.R, params.P)
+		}
+		if 128*uint64(params.N)*uint64(params.R) > maxKDFMemory || uint64(params.N)*uint64(params.R)*uint64(params.P) > maxKDFWork {
+			return fmt.Errorf("%s parameters n=%d r=%d p=%d exceed the supported cost", KDFScrypt, params.N, params.R, params.P)
+		}
+	case KDFArgon2id:
+		if params.T == 0 || params.P < 1 || params.P > 255 || params.M < 8*uint32(params.P) {
+			return fmt.Errorf("invalid %s parameters t=%d m=%d p=%d", KDFArgon2id, params.T, params.M, params.P)
+		}
+		if uint64(params.M)*1024 > maxKDFMemory || params.T > maxArgon2Passes {
+			return fmt.Errorf("%s parameters t=%d m=%d exceed the supported cost", KDFArgon2id, params.T, params.M)
+		}
+	default:
+		return fmt.Errorf("unsupported KDF %q", params.Name)
+	}
+	return nil
+}
+
+func newGCM(dk []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(dk)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
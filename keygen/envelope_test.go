@@ -0,0 +1,276 @@
+// keygen/envelope_test.go
+
+package keygen
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// encryptLegacy writes a key the way FormatVersionLegacy files were written: salt, nonce, ciphertext
+func encryptLegacy(t *testing.T, key []byte, passphrase string) []byte {
+	t.Helper()
+	salt := make([]byte, legacySaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		t.Fatal(err)
+	}
+	dk, err := scrypt.Key([]byte(passphrase), salt, 32768, 8, 1, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcm, err := newGCM(dk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		t.Fatal(err)
+	}
+	data := append(salt, nonce...)
+	return gcm.Seal(data, nonce, key, nil)
+}
+
+func TestDecryptLegacyFile(t *testing.T) {
+	ks := newTestKeystore(t)
+	privateKey, _ := crypto.GenerateKey()
+	legacy := encryptLegacy(t, crypto.FromECDSA(privateKey), "correct horse")
+	if err := os.WriteFile(ks.keyFile("legacy"), legacy, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	envelope, err := ReadEnvelope(ks.keyFile("legacy"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if envelope.Version != FormatVersionLegacy || envelope.KDFParams.N != 32768 {
+		t.Errorf("envelope = version %d n=%d, want the legacy layout", envelope.Version, envelope.KDFParams.N)
+	}
+	if _, err := envelope.Decrypt("wrong"); err == nil {
+		t.Error("decrypted a legacy file with the wrong password")
+	}
+
+	decrypted, err := ks.PrivateKey("legacy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decrypted.Equal(privateKey) {
+		t.Error("legacy file decrypted to a different key")
+	}
+
+	upgraded, err := ks.Migrate("legacy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(upgraded) != 1 {
+		t.Fatalf("upgraded = %v, want the key file", upgraded)
+	}
+	account, err := ks.Account("legacy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.Version != FormatVersion || account.Address != crypto.PubkeyToAddress(privateKey.PublicKey) {
+		t.Errorf("migrated account = %+v", account)
+	}
+}
+
+func TestMigrateToKeystoreKDFParams(t *testing.T) {
+	ks := newTestKeystore(t)
+	if _, err := ks.CreateAccount("alice"); err != nil {
+		t.Fatal(err)
+	}
+	if upgraded, err := ks.Migrate("alice"); err != nil || len(upgraded) != 0 {
+		t.Fatalf("Migrate = %v, %v; want nothing to upgrade", upgraded, err)
+	}
+
+	ks.KDFParams = KDFParams{Name: KDFArgon2id, T: 1, M: 64, P: 2, KeyLen: 32}
+	upgraded, err := ks.Migrate("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(upgraded) != 1 {
+		t.Fatalf("upgraded = %v, want the key file", upgraded)
+	}
+	envelope, err := ReadEnvelope(ks.keyFile("alice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sameKDFParams(envelope.KDFParams, ks.KDFParams) {
+		t.Errorf("params = %+v, want %+v", envelope.KDFParams, ks.KDFParams)
+	}
+}
+
+func TestArgon2idRoundTrip(t *testing.T) {
+	params := KDFParams{Name: KDFArgon2id, T: 1, M: 64, P: 2, KeyLen: 32}
+	key := bytes.Repeat([]byte{0x42}, 32)
+	metadata := KeyMetadata{Address: "0x0000000000000000000000000000000000000001", Source: SourceImported}
+
+	data, err := EncryptKeyWithMetadata(key, "correct horse", metadata, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	envelope, err := ParseEnvelope(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if envelope.Version != FormatVersion || envelope.KDF != KDFArgon2id {
+		t.Errorf("envelope = version %d kdf %q", envelope.Version, envelope.KDF)
+	}
+	if !sameKDFParams(envelope.KDFParams, params) || envelope.KDFParams.Salt == "" {
+		t.Errorf("params = %+v, want %+v with a salt", envelope.KDFParams, params)
+	}
+	if envelope.Address != metadata.Address || envelope.Source != metadata.Source {
+		t.Errorf("metadata = %+v, want %+v", envelope.KeyMetadata, metadata)
+	}
+
+	decrypted, err := envelope.Decrypt("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decrypted, key) {
+		t.Error("decrypted a different key")
+	}
+	if _, err := envelope.Decrypt("wrong"); err == nil {
+		t.Error("decrypted with the wrong password")
+	}
+}
+
+// encryptUnauthenticatedHeader writes a key the way FormatVersionUnauthenticatedHeader files were
+// written: a JSON envelope sealed without additional data
+func encryptUnauthenticatedHeader(t *testing.T, key []byte, passphrase string, metadata KeyMetadata) []byte {
+	t.Helper()
+	params := testKDFParams
+	params.Salt = hex.EncodeToString(bytes.Repeat([]byte{0x01}, 16))
+	dk, err := deriveKey(passphrase, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcm, err := newGCM(dk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	data, err := json.Marshal(Envelope{
+		Version:     FormatVersionUnauthenticatedHeader,
+		KeyMetadata: metadata,
+		Cipher:      CipherAES256GCM,
+		KDF:         params.Name,
+		KDFParams:   params,
+		Nonce:       hex.EncodeToString(nonce),
+		Ciphertext:  hex.EncodeToString(gcm.Seal(nil, nonce, key, nil)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestDecryptRejectsChangedHeader(t *testing.T) {
+	key := bytes.Repeat([]byte{0x42}, 32)
+	metadata := KeyMetadata{Address: "0x0000000000000000000000000000000000000001", Source: SourceImported}
+	data, err := EncryptKeyWithMetadata(key, "correct horse", metadata, testKDFParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecryptKey(data, "correct horse"); err != nil {
+		t.Fatal(err)
+	}
+
+	tampered := strings.Replace(string(data), metadata.Address, "0x0000000000000000000000000000000000000002", 1)
+	if _, err := DecryptKey([]byte(tampered), "correct horse"); err == nil {
+		t.Error("decrypted a file whose address was changed")
+	}
+	tampered = strings.Replace(string(data), SourceImported, SourceGenerated, 1)
+	if _, err := DecryptKey([]byte(tampered), "correct horse"); err == nil {
+		t.Error("decrypted a file whose source was changed")
+	}
+}
+
+func TestMigrateUnauthenticatedHeader(t *testing.T) {
+	ks := newTestKeystore(t)
+	privateKey, _ := crypto.GenerateKey()
+	metadata := newKeyMetadata(crypto.PubkeyToAddress(privateKey.PublicKey), SourceImported)
+	data := encryptUnauthenticatedHeader(t, crypto.FromECDSA(privateKey), "correct horse", metadata)
+	if err := os.WriteFile(ks.keyFile("alice"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	decrypted, err := ks.PrivateKey("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decrypted.Equal(privateKey) {
+		t.Error("version 2 file decrypted to a different key")
+	}
+
+	upgraded, err := ks.Migrate("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(upgraded) != 1 {
+		t.Fatalf("upgraded = %v, want the key file", upgraded)
+	}
+	account, err := ks.Account("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.Version != FormatVersion || account.Address.Hex() != metadata.Address || !account.Created.Equal(*metadata.Created) {
+		t.Errorf("migrated account = %+v, want the version 2 header at version %d", account, FormatVersion)
+	}
+}
+
+func TestParseEnvelope(t *testing.T) {
+	data, err := EncryptKeyWithMetadata(bytes.Repeat([]byte{0x42}, 32), "correct horse", KeyMetadata{}, testKDFParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseEnvelope(data[:len(data)/2]); err == nil {
+		t.Error("parsed a truncated envelope as a legacy file")
+	}
+	if _, err := ParseEnvelope([]byte(`{"version": 9}`)); err == nil {
+		t.Error("parsed an unknown version")
+	}
+	if _, err := ParseEnvelope([]byte(`{"cipher": "aes-256-gcm"}`)); err == nil {
+		t.Error("parsed an envelope without a version")
+	}
+
+	// A legacy salt can start with "{" too
+	legacy := append([]byte{'{', 0xff}, bytes.Repeat([]byte{0x80}, 60)...)
+	envelope, err := ParseEnvelope(legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if envelope.Version != FormatVersionLegacy {
+		t.Errorf("version = %d, want the legacy layout", envelope.Version)
+	}
+}
+
+func TestValidateKDFParamsBounds(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  KDFParams
+		wantErr bool
+	}{
+		{"default scrypt", DefaultScryptParams, false},
+		{"default argon2id", DefaultArgon2idParams, false},
+		{"scrypt at 1 GiB", KDFParams{Name: KDFScrypt, N: 1 << 20, R: 8, P: 1, KeyLen: 32}, false},
+		{"scrypt over the memory bound", KDFParams{Name: KDFScrypt, N: 1 << 22, R: 8, P: 1, KeyLen: 32}, true},
+		{"scrypt over the work bound", KDFParams{Name: KDFScrypt, N: 1 << 15, R: 8, P: 1 << 10, KeyLen: 32}, true},
+		{"argon2id over the memory bound", KDFParams{Name: KDFArgon2id, T: 1, M: 4 << 20, P: 4, KeyLen: 32}, true},
+		{"argon2id over the pass bound", KDFParams{Name: KDFArgon2id, T: 1000, M: 64 * 1024, P: 4, KeyLen: 32}, true},
+	}
+	for _, tt := range tests {
+		if err := ValidateKDFParams(tt.params); (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
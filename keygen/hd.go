@@ -0,0 +1,239 @@
+// keygen/hd.go
+
+package keygen
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// hardenedKeyStart is the first BIP-32 hardened child index
+const hardenedKeyStart = 0x80000000
+
+// MaxDeriveAddresses bounds how many addresses one DeriveAddresses call derives
+const MaxDeriveAddresses = 1000
+
+var masterKeySalt = []byte("Bitcoin seed")
+
+// DefaultHDBasePath is the BIP-44 base path whose children m/44'/60'/0'/0/i are the account addresses
+var DefaultHDBasePath = accounts.DefaultRootDerivationPath
+
+// DerivePrivateKey derives the BIP-32 private key for path from a BIP-39 seed
+func DerivePrivateKey(seed []byte, path accounts.DerivationPath) ([]byte, error) {
+	mac := hmac.New(sha512.New, masterKeySalt)
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+
+	key, chainCode := sum[:32], sum[32:]
+	if err := checkKey(new(big.Int).SetBytes(key)); err != nil {
+		return nil, fmt.Errorf("invalid master key: %v", err)
+	}
+
+	for _, index := range path {
+		var data []byte
+		if index >= hardenedKeyStart {
+			data = append([]byte{0x00}, key...)
+		} else {
+			privateKey, err := crypto.ToECDSA(key)
+			if err != nil {
+				return nil, err
+			}
+			data = crypto.CompressPubkey(&privateKey.PublicKey)
+		}
+		data = binary.BigEndian.AppendUint32(data, index)
+
+		mac := hmac.New(sha512.New, chainCode)
+		mac.Write(data)
+		sum := mac.Sum(nil)
+
+		tweak := new(big.Int).SetBytes(sum[:32])
+		if tweak.Cmp(crypto.S256().Params().N) >= 0 {
+			return nil, fmt.Errorf("invalid child key at index %d", index)
+		}
+
+		child := new(big.Int).Add(tweak, new(big.Int).SetBytes(key))
+		child.Mod(child, crypto.S256().Params().N)
+		if err := checkKey(child); err != nil {
+			return nil, fmt.Errorf("invalid child key at index %d: %v", index, err)
+		}
+
+		key, chainCode = math.PaddedBigBytes(child, 32), sum[32:]
+	}
+
+	return key, nil
+}
+
+func checkKey(key *big.Int) error {
+	if key.Sign() == 0 || key.Cmp(crypto.S256().Params().N) >= 0 {
+		return fmt.Errorf("key out of range")
+	}
+	return nil
+}
+
+// CreateHDAccount saves only the seed and the base derivation path, from which any number of
+// addresses are derived as <base path>/i
+func (ks *Keystore) CreateHDAccount(accountName, mnemonic, passphrase string, basePath accounts.DerivationPath) (Account, error) {
+	if err := ks.checkNewAccount(accountName); err != nil {
+		return Account{}, err
+	}
+
+	seed, err := SeedFromMnemonic(mnemonic, passphrase)
+	if err != nil {
+		return Account{}, err
+	}
+
+	if _, err := DeriveAddresses(seed, basePath, 0, 1); err != nil {
+		return Account{}, fmt.Errorf("failed to derive address: %v", err)
+	}
+
+	password, err := ks.passwords.NewPassword(accountName)
+	if err != nil {
+		return Account{}, fmt.Errorf("failed to read password: %v", err)
+	}
+
+	if err := ks.storeSeed(accountName, seed, basePath, password); err != nil {
+		return Account{}, err
+	}
+	if err := ks.removeReplacedFiles(accountName, ks.seedFile(accountName)); err != nil {
+		return Account{}, err
+	}
+
+	return ks.describe(accountName)
+}
+
+// IsHDAccount reports whether the account has a stored seed to derive addresses from
+func (ks *Keystore) IsHDAccount(accountName string) bool {
+	return fileExists(ks.seedFile(accountName))
+}
+
+// Seed decrypts the seed stored as <account>.seed.enc
+func (ks *Keystore) Seed(accountName string) ([]byte, error) {
+	return ks.unlock(accountName, ks.seedFile(accountName))
+}
+
+// DeriveAddresses returns count addresses of the account starting at index start
+func (ks *Keystore) DeriveAddresses(accountName string, start, count uint32) ([]common.Address, error) {
+	if err := checkAddressRange(start, count); err != nil {
+		return nil, err
+	}
+
+	basePath, err := ks.DerivationPath(accountName)
+	if err != nil {
+		return nil, err
+	}
+
+	seed, err := ks.Seed(accountName)
+	if err != nil {
+		return nil, err
+	}
+
+	return DeriveAddresses(seed, basePath, start, count)
+}
+
+// DerivedPrivateKey derives the private key at the given index below the account's base path
+func (ks *Keystore) DerivedPrivateKey(accountName string, index uint32) (*ecdsa.PrivateKey, error) {
+	if err := checkAddressRange(index, 1); err != nil {
+		return nil, err
+	}
+
+	basePath, err := ks.DerivationPath(accountName)
+	if err != nil {
+		return nil, err
+	}
+
+	seed, err := ks.Seed(accountName)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKeyBytes, err := DerivePrivateKey(seed, ChildPath(basePath, index))
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive private key: %v", err)
+	}
+
+	return crypto.ToECDSA(privateKeyBytes)
+}
+
+// DerivationPath returns the account's base derivation path from the seed file header.
+// Files written before the header existed keep the path in <account>.path, or use DefaultHDBasePath.
+func (ks *Keystore) DerivationPath(accountName string) (accounts.DerivationPath, error) {
+	envelope, err := ReadEnvelope(ks.seedFile(accountName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s has no seed", ErrAccountNotFound, accountName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read seed file: %v", err)
+	}
+
+	pathInput := envelope.Path
+	if pathInput == "" {
+		data, err := os.ReadFile(ks.legacyPathFile(accountName))
+		if errors.Is(err, os.ErrNotExist) {
+			return DefaultHDBasePath, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read derivation path file: %v", err)
+		}
+		pathInput = strings.TrimSpace(string(data))
+	}
+
+	basePath, err := accounts.ParseDerivationPath(pathInput)
+	if err != nil {
+		return nil, fmt.Errorf("invalid derivation path: %v", err)
+	}
+	return basePath, nil
+}
+
+// DeriveAddresses returns count addresses starting at index start below the base path.
+// At most MaxDeriveAddresses are derived, and every index must be below the hardened range.
+func DeriveAddresses(seed []byte, basePath accounts.DerivationPath, start, count uint32) ([]common.Address, error) {
+	if err := checkAddressRange(start, count); err != nil {
+		return nil, err
+	}
+
+	addresses := make([]common.Address, 0, count)
+	for index := start; index < start+count; index++ {
+		privateKeyBytes, err := DerivePrivateKey(seed, ChildPath(basePath, index))
+		if err != nil {
+			return nil, err
+		}
+
+		privateKey, err := crypto.ToECDSA(privateKeyBytes)
+		if err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, crypto.PubkeyToAddress(privateKey.PublicKey))
+	}
+	return addresses, nil
+}
+
+// checkAddressRange rejects more than MaxDeriveAddresses addresses and indices that would
+// wrap around or reach the hardened range, where ChildPath would silently derive other keys
+func checkAddressRange(start, count uint32) error {
+	if count > MaxDeriveAddresses {
+		return fmt.Errorf("cannot derive %d addresses at once, the maximum is %d", count, MaxDeriveAddresses)
+	}
+	if uint64(start)+uint64(count) > hardenedKeyStart {
+		return fmt.Errorf("address index must be below %d", uint32(hardenedKeyStart))
+	}
+	return nil
+}
+
+// ChildPath returns the derivation path of the address at index below the base path
+func ChildPath(basePath accounts.DerivationPath, index uint32) accounts.DerivationPath {
+	path := make(accounts.DerivationPath, len(basePath), len(basePath)+1)
+	copy(path, basePath)
+	return append(path, index)
+}
@@ -0,0 +1,97 @@
+// keygen/hd_test.go
+
+package keygen
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// The first address of the BIP-39 "abandon ... about" test mnemonic at m/44'/60'/0'/0/0
+var testMnemonicAddress = common.HexToAddress("0x9858EfFD232B4033E47d90003D41EC34EcaEda94")
+
+func TestDerivePrivateKeyVector(t *testing.T) {
+	seed, err := SeedFromMnemonic(testMnemonic, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := DerivePrivateKey(seed, ChildPath(DefaultHDBasePath, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateKey, err := crypto.ToECDSA(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if address := crypto.PubkeyToAddress(privateKey.PublicKey); address != testMnemonicAddress {
+		t.Errorf("address = %s, want %s", address.Hex(), testMnemonicAddress.Hex())
+	}
+
+	addresses, err := DeriveAddresses(seed, DefaultHDBasePath, 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addresses) != 2 || addresses[0] != testMnemonicAddress || addresses[1] == addresses[0] {
+		t.Errorf("addresses = %v", addresses)
+	}
+}
+
+func TestHDAccountUsesVector(t *testing.T) {
+	ks := newTestKeystore(t)
+	account, err := ks.CreateHDAccount("alice", testMnemonic, "", DefaultHDBasePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.Address != testMnemonicAddress {
+		t.Errorf("address = %s, want %s", account.Address.Hex(), testMnemonicAddress.Hex())
+	}
+
+	privateKey, err := ks.DerivedPrivateKey("alice", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if crypto.PubkeyToAddress(privateKey.PublicKey) != testMnemonicAddress {
+		t.Error("derived key does not match the account address")
+	}
+}
+
+func TestDeriveAddressesRange(t *testing.T) {
+	seed, err := SeedFromMnemonic(testMnemonic, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		start, count uint32
+		wantErr      bool
+	}{
+		{"none", 0, 0, false},
+		{"last unhardened index", hardenedKeyStart - 1, 1, false},
+		{"too many", 0, MaxDeriveAddresses + 1, true},
+		{"hardened start", hardenedKeyStart, 1, true},
+		{"runs into the hardened range", hardenedKeyStart - 1, 2, true},
+		{"wraps around", 0xFFFFFFFF, 2, true},
+	}
+	for _, tt := range tests {
+		addresses, err := DeriveAddresses(seed, DefaultHDBasePath, tt.start, tt.count)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v, want error %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err == nil && len(addresses) != int(tt.count) {
+			t.Errorf("%s: got %d addresses, want %d", tt.name, len(addresses), tt.count)
+		}
+	}
+
+	ks := newTestKeystore(t)
+	if _, err := ks.CreateHDAccount("alice", testMnemonic, "", DefaultHDBasePath); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks.DerivedPrivateKey("alice", hardenedKeyStart); err == nil {
+		t.Error("derived a key at a hardened index")
+	}
+}
@@ -0,0 +1,164 @@
+// keygen/kdf.go
+
+package keygen
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Environment variables that tune the KDF of newly written files per deployment
+const (
+	EnvKDF          = "KEYGEN_KDF"
+	EnvScryptN      = "KEYGEN_SCRYPT_N"
+	EnvScryptR      = "KEYGEN_SCRYPT_R"
+	EnvArgon2Time   = "KEYGEN_ARGON2_TIME"
+	EnvArgon2Memory = "KEYGEN_ARGON2_MEMORY"
+	EnvKDFThreads   = "KEYGEN_KDF_PARALLELISM"
+)
+
+// maxBenchmarkMemory bounds the memory of the parameters the benchmark suggests (1 GiB)
+const maxBenchmarkMemory = 1 << 30
+
+// maxBenchmarkPasses bounds the Argon2id passes the benchmark adds once the memory is capped
+const maxBenchmarkPasses = 10
+
+// ErrBenchmarkLimit is returned when the target unlock time is not reached within the benchmark's limits
+var ErrBenchmarkLimit = errors.New("target unlock time not reached within the benchmark limits")
+
+// KDFParamsFromEnv returns DefaultScryptParams overridden by the KEYGEN_* environment variables
+func KDFParamsFromEnv() (KDFParams, error) {
+	var params KDFParams
+	switch name := os.Getenv(EnvKDF); name {
+	case "", KDFScrypt:
+		params = DefaultScryptParams
+	case KDFArgon2id:
+		params = DefaultArgon2idParams
+	default:
+		return KDFParams{}, fmt.Errorf("unsupported %s %q", EnvKDF, name)
+	}
+
+	overrides := []struct {
+		env   string
+		apply func(uint64)
+		kdf   string
+	}{
+		{EnvScryptN, func(v uint64) { params.N = int(v) }, KDFScrypt},
+		{EnvScryptR, func(v uint64) { params.R = int(v) }, KDFScrypt},
+		{EnvArgon2Time, func(v uint64) { params.T = uint32(v) }, KDFArgon2id},
+		{EnvArgon2Memory, func(v uint64) { params.M = uint32(v) }, KDFArgon2id},
+		{EnvKDFThreads, func(v uint64) { params.P = int(v) }, ""},
+	}
+	for _, override := range overrides {
+		raw := os.Getenv(override.env)
+		if raw == "" {
+			continue
+		}
+		if override.kdf != "" && override.kdf != params.Name {
+			return KDFParams{}, fmt.Errorf("%s does not apply to %s", override.env, params.Name)
+		}
+		value, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil || value == 0 {
+			return KDFParams{}, fmt.Errorf("invalid %s %q", override.env, raw)
+		}
+		override.apply(value)
+	}
+
+	if err := ValidateKDFParams(params); err != nil {
+		return KDFParams{}, err
+	}
+	return params, nil
+}
+
+// SuggestArgon2idParams doubles the Argon2id memory, then adds passes, until one derivation
+// takes at least target. The memory stays within 1 GiB and half the available memory, and the
+// passes within 10; beyond that it stops with ErrBenchmarkLimit and the last parameters tried.
+func SuggestArgon2idParams(target time.Duration) (KDFParams, time.Duration, error) {
+	return suggestArgon2idParams(target, benchmarkMemoryLimit(), maxBenchmarkPasses)
+}
+
+func suggestArgon2idParams(target time.Duration, memoryLimit uint64, maxPasses uint32) (KDFParams, time.Duration, error) {
+	params := DefaultArgon2idParams
+	params.T = 1
+	params.M = 16 * 1024
+	params.P = min(runtime.NumCPU(), 4)
+
+	for {
+		duration, err := benchmarkKDF(params)
+		if err != nil || duration >= target {
+			return params, duration, err
+		}
+		switch {
+		case uint64(params.M)*2*1024 <= memoryLimit:
+			params.M *= 2
+		case params.T < maxPasses:
+			params.T++
+		default:
+			return params, duration, fmt.Errorf("%w: %s takes %s with t=%d m=%d KiB",
+				ErrBenchmarkLimit, KDFArgon2id, duration.Round(time.Millisecond), params.T, params.M)
+		}
+	}
+}
+
+// SuggestScryptParams doubles the scrypt cost N until one derivation takes at least target.
+// The memory, 128*N*r bytes, stays within the same limit as SuggestArgon2idParams.
+func SuggestScryptParams(target time.Duration) (KDFParams, time.Duration, error) {
+	return suggestScryptParams(target, benchmarkMemoryLimit())
+}
+
+func suggestScryptParams(target time.Duration, memoryLimit uint64) (KDFParams, time.Duration, error) {
+	params := DefaultScryptParams
+	params.N = 1 << 14
+
+	for {
+		duration, err := benchmarkKDF(params)
+		if err != nil || duration >= target {
+			return params, duration, err
+		}
+		if 128*uint64(params.N)*2*uint64(params.R) > memoryLimit {
+			return params, duration, fmt.Errorf("%w: %s takes %s with n=%d r=%d",
+				ErrBenchmarkLimit, KDFScrypt, duration.Round(time.Millisecond), params.N, params.R)
+		}
+		params.N *= 2
+	}
+}
+
+// benchmarkMemoryLimit is maxBenchmarkMemory, or half the available memory when that is less
+func benchmarkMemoryLimit() uint64 {
+	limit := uint64(maxBenchmarkMemory)
+	if available, ok := availableMemory(); ok && available/2 < limit {
+		limit = available / 2
+	}
+	return limit
+}
+
+// availableMemory reads MemAvailable from /proc/meminfo; it reports false where that is missing
+func availableMemory() (uint64, bool) {
+	data, err := os.ReadFile("/proc/meminfo")
+	if err != nil {
+		return 0, false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "MemAvailable:" {
+			kib, err := strconv.ParseUint(fields[1], 10, 64)
+			if err != nil {
+				return 0, false
+			}
+			return kib * 1024, true
+		}
+	}
+	return 0, false
+}
+
+func benchmarkKDF(params KDFParams) (time.Duration, error) {
+	params.Salt = "00000000000000000000000000000000"
+	start := time.Now()
+	_, err := deriveKey("benchmark", params)
+	return time.Since(start), err
+}
@@ -0,0 +1,39 @@
+// keygen/kdf_test.go
+
+package keygen
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSuggestScryptParamsStopsAtMemoryLimit(t *testing.T) {
+	params, _, err := suggestScryptParams(time.Hour, 32<<20)
+	if !errors.Is(err, ErrBenchmarkLimit) {
+		t.Fatalf("err = %v, want ErrBenchmarkLimit", err)
+	}
+	if memory := 128 * params.N * params.R; memory > 32<<20 {
+		t.Errorf("n=%d r=%d uses %d bytes, above the 32 MiB limit", params.N, params.R, memory)
+	}
+}
+
+func TestSuggestArgon2idParamsStopsAtLimits(t *testing.T) {
+	params, _, err := suggestArgon2idParams(time.Hour, 32<<20, 2)
+	if !errors.Is(err, ErrBenchmarkLimit) {
+		t.Fatalf("err = %v, want ErrBenchmarkLimit", err)
+	}
+	if params.M > 32*1024 || params.T > 2 {
+		t.Errorf("t=%d m=%d KiB, want at most 2 passes and 32 MiB", params.T, params.M)
+	}
+}
+
+func TestSuggestScryptParamsReachesTarget(t *testing.T) {
+	params, duration, err := suggestScryptParams(time.Nanosecond, 1<<30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params.N != 1<<14 || duration <= 0 {
+		t.Errorf("n=%d in %s, want the first candidate", params.N, duration)
+	}
+}
@@ -3,267 +3,360 @@
 package keygen
 
 import (
-	"crypto/aes"
-	"crypto/cipher"
 	"crypto/ecdsa"
-	"crypto/rand"
-	"encoding/hex"
+	"errors"
 	"fmt"
-	"github.com/ethereum/go-ethereum/crypto"
-	"golang.org/x/crypto/scrypt"
-	"golang.org/x/crypto/ssh/terminal"
-	"log"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"go-ethereum-wallet/internal/atomicfile"
 )
 
 const AccountPath = "./account"
 
-func CreateAccount() {
-	fmt.Print("Enter account name: ")
-	var accountName string
-	fmt.Scanln(&accountName)
+// ErrAccountNotFound is returned when the store has no files for an account
+var ErrAccountNotFound = errors.New("account not found")
 
-	// Generate a new private key
-	privateKey, err := ecdsa.GenerateKey(crypto.S256(), rand.Reader)
-	if err != nil {
-		log.Fatalf("Failed to generate private key: %v", err)
-	}
+// ErrAccountExists is returned when creating or importing an account under a name already in use
+var ErrAccountExists = errors.New("account already exists")
 
-	// Convert the private key to bytes
-	privateKeyBytes := crypto.FromECDSA(privateKey)
-	privateKeyHex := hex.EncodeToString(privateKeyBytes)
-	fmt.Printf("Private Key: %s\n", privateKeyHex)
+// PasswordProvider supplies the passwords that unlock and encrypt accounts
+type PasswordProvider interface {
+	// Password returns the password of an existing account
+	Password(accountName string) (string, error)
+	// NewPassword returns the password to encrypt a new or re-encrypted account with
+	NewPassword(accountName string) (string, error)
+}
 
-	// Get the corresponding public address
-	publicKey := privateKey.Public()
-	publicKeyECDSA, ok := publicKey.(*ecdsa.PublicKey)
-	if !ok {
-		log.Fatalf("Failed to cast public key to ECDSA")
-	}
+// StaticPassword uses the same password for every account
+type StaticPassword string
 
-	address := crypto.PubkeyToAddress(*publicKeyECDSA).Hex()
-	fmt.Printf("Public Address: %s\n", address)
+func (p StaticPassword) Password(accountName string) (string, error) {
+	return string(p), nil
+}
 
-	// Save the private key to a file
-	fmt.Print("Enter a password to encrypt the private key: ")
-	password, err := terminal.ReadPassword(0)
-	if err != nil {
-		log.Fatalf("Failed to read password: %v", err)
-	}
-	fmt.Println()
+func (p StaticPassword) NewPassword(accountName string) (string, error) {
+	return string(p), nil
+}
 
-	encryptedKey, err := EncryptKey(privateKeyBytes, string(password))
-	if err != nil {
-		log.Fatalf("Failed to encrypt private key: %v", err)
-	}
+// Account describes an account in the store
+type Account struct {
+	Name    string
+	Address common.Address
+	// Path is the base derivation path of accounts backed by a seed
+	Path accounts.DerivationPath
+	// Source is SourceGenerated, SourceImported or SourceDerived, or empty for legacy files
+	Source  string
+	Created time.Time
+	// Version is the format version of the account's file
+	Version int
+}
 
-	filePath := filepath.Join(AccountPath, accountName+".enc")
-	err = os.MkdirAll(filepath.Dir(filePath), 0755)
-	if err != nil {
-		log.Fatalf("Failed to create directories: %v", err)
+// Keystore manages the encrypted accounts in a directory. Creating or importing an account
+// under a name already in use fails with ErrAccountExists unless Overwrite is set; the
+// replaced account's files are then removed, so no key or seed of it is left behind.
+// KDFParams are used for every file the keystore writes; NewKeystore sets DefaultScryptParams.
+type Keystore struct {
+	Overwrite bool
+	KDFParams KDFParams
+
+	dir       string
+	passwords PasswordProvider
+}
+
+func NewKeystore(dir string, passwords PasswordProvider) *Keystore {
+	return &Keystore{
+		KDFParams: DefaultScryptParams,
+		dir:       dir,
+		passwords: passwords,
 	}
+}
 
-	err = os.WriteFile(filePath, encryptedKey, 0644)
+// CreateAccount generates a new random key and saves it as <account>.enc
+func (ks *Keystore) CreateAccount(accountName string) (Account, error) {
+	privateKey, err := crypto.GenerateKey()
 	if err != nil {
-		log.Fatalf("Failed to save private key: %v", err)
+		return Account{}, fmt.Errorf("failed to generate private key: %v", err)
 	}
 
-	fmt.Printf("Private key successfully saved to '%s'\n", filePath)
+	return ks.importPrivateKey(accountName, crypto.FromECDSA(privateKey), SourceGenerated)
 }
 
-func GetAddressForAccount() {
-	fmt.Print("Enter account name: ")
-	var accountName string
-	fmt.Scanln(&accountName)
+// ImportPrivateKey saves an existing raw private key as <account>.enc
+func (ks *Keystore) ImportPrivateKey(accountName string, privateKeyBytes []byte) (Account, error) {
+	return ks.importPrivateKey(accountName, privateKeyBytes, SourceImported)
+}
 
-	filePath := filepath.Join(AccountPath, accountName+".enc")
-	encryptedKey, err := os.ReadFile(filePath)
-	if err != nil {
-		log.Fatalf("Failed to read private key file: %v", err)
+func (ks *Keystore) importPrivateKey(accountName string, privateKeyBytes []byte, source string) (Account, error) {
+	if err := ks.checkNewAccount(accountName); err != nil {
+		return Account{}, err
 	}
-
-	fmt.Print("Enter password: ")
-	password, err := terminal.ReadPassword(0)
-	if err != nil {
-		log.Fatalf("Failed to read password: %v", err)
+	if _, err := crypto.ToECDSA(privateKeyBytes); err != nil {
+		return Account{}, fmt.Errorf("invalid private key: %v", err)
 	}
-	fmt.Println()
 
-	privateKeyBytes, err := DecryptKey(encryptedKey, string(password))
+	password, err := ks.passwords.NewPassword(accountName)
 	if err != nil {
-		log.Fatalf("Failed to decrypt private key: %v", err)
+		return Account{}, fmt.Errorf("failed to read password: %v", err)
 	}
 
-	privateKey, err := crypto.ToECDSA(privateKeyBytes)
+	account, err := ks.storePrivateKey(accountName, privateKeyBytes, password, source)
 	if err != nil {
-		log.Fatalf("Failed to convert bytes to ECDSA: %v", err)
+		return Account{}, err
 	}
-
-	publicKey := privateKey.Public()
-	publicKeyECDSA, ok := publicKey.(*ecdsa.PublicKey)
-	if !ok {
-		log.Fatalf("Failed to cast public key to ECDSA")
+	if err := ks.removeReplacedFiles(accountName, ks.keyFile(accountName)); err != nil {
+		return Account{}, err
 	}
-
-	address := crypto.PubkeyToAddress(*publicKeyECDSA).Hex()
-	fmt.Printf("Public Address: %s\n", address)
+	return account, nil
 }
 
-func GetPrivateKeyForAccount() {
-	fmt.Print("Enter account name: ")
-	var accountName string
-	fmt.Scanln(&accountName)
-
-	privateKeyHex, err := RetrievePrivateKeyHex(accountName)
-	if err != nil {
-		log.Fatalf("Failed to retrieve private key: %v", err)
+// checkNewAccount fails with ErrAccountExists when any file of the account exists, unless Overwrite is set
+func (ks *Keystore) checkNewAccount(accountName string) error {
+	if ks.Overwrite {
+		return nil
 	}
-
-	fmt.Printf("Private Key: %s\n", privateKeyHex)
+	for _, filePath := range []string{ks.keyFile(accountName), ks.seedFile(accountName), ks.legacyPathFile(accountName)} {
+		if fileExists(filePath) {
+			return fmt.Errorf("%w: %s", ErrAccountExists, accountName)
+		}
+	}
+	return nil
 }
 
-func SaveAccountWithPrivateKey() {
-	fmt.Print("Enter account name: ")
-	var accountName string
-	fmt.Scanln(&accountName)
-
-	fmt.Print("Enter private key (hex format): ")
-	var privateKeyHex string
-	fmt.Scanln(&privateKeyHex)
+// removeReplacedFiles deletes the files of an overwritten account that the new account does not use
+func (ks *Keystore) removeReplacedFiles(accountName string, keep ...string) error {
+	for _, filePath := range []string{ks.keyFile(accountName), ks.seedFile(accountName), ks.legacyPathFile(accountName)} {
+		kept := false
+		for _, k := range keep {
+			kept = kept || k == filePath
+		}
+		if kept {
+			continue
+		}
+		if err := os.Remove(filePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove '%s' of the replaced account: %v", filePath, err)
+		}
+	}
+	return nil
+}
 
-	privateKeyBytes, err := hex.DecodeString(privateKeyHex)
+// Account returns the account's address and metadata. Only files written before the
+// format had a header need the password for this.
+func (ks *Keystore) Account(accountName string) (Account, error) {
+	account, err := ks.describe(accountName)
 	if err != nil {
-		log.Fatalf("Failed to decode private key: %v", err)
+		return Account{}, err
 	}
-
-	privateKey, err := crypto.ToECDSA(privateKeyBytes)
-	if err != nil {
-		log.Fatalf("Invalid private key: %v", err)
+	if account.Address != (common.Address{}) {
+		return account, nil
 	}
 
-	// Get the corresponding public address
-	publicKey := privateKey.Public()
-	publicKeyECDSA, ok := publicKey.(*ecdsa.PublicKey)
-	if !ok {
-		log.Fatalf("Failed to cast public key to ECDSA")
+	if fileExists(ks.keyFile(accountName)) {
+		privateKey, err := ks.PrivateKey(accountName)
+		if err != nil {
+			return Account{}, err
+		}
+		account.Address = crypto.PubkeyToAddress(privateKey.PublicKey)
+		return account, nil
 	}
 
-	address := crypto.PubkeyToAddress(*publicKeyECDSA).Hex()
-	fmt.Printf("Public Address: %s\n", address)
-
-	// Save the private key to a file
-	fmt.Print("Enter a password to encrypt the private key: ")
-	password, err := terminal.ReadPassword(0)
+	addresses, err := ks.DeriveAddresses(accountName, 0, 1)
 	if err != nil {
-		log.Fatalf("Failed to read password: %v", err)
+		return Account{}, err
 	}
-	fmt.Println()
+	account.Address = addresses[0]
+	return account, nil
+}
 
-	encryptedKey, err := EncryptKey(privateKeyBytes, string(password))
-	if err != nil {
-		log.Fatalf("Failed to encrypt private key: %v", err)
+// ListAccounts describes every account in the store from the file headers, without any password.
+// The address of files written before the format had a header is left empty.
+func (ks *Keystore) ListAccounts() ([]Account, error) {
+	entries, err := os.ReadDir(ks.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
 	}
-
-	filePath := filepath.Join(AccountPath, accountName+".enc")
-	err = os.MkdirAll(filepath.Dir(filePath), 0755)
 	if err != nil {
-		log.Fatalf("Failed to create directories: %v", err)
-	}
+		return nil, fmt.Errorf("failed to read account directory: %v", err)
+	}
+
+	var names []string
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		fileName := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(fileName, ".") {
+			continue
+		}
+
+		var accountName string
+		switch {
+		case strings.HasSuffix(fileName, ".seed.enc"):
+			accountName = strings.TrimSuffix(fileName, ".seed.enc")
+		case strings.HasSuffix(fileName, ".enc"):
+			accountName = strings.TrimSuffix(fileName, ".enc")
+		default:
+			continue
+		}
+
+		if !seen[accountName] {
+			seen[accountName] = true
+			names = append(names, accountName)
+		}
+	}
+	sort.Strings(names)
+
+	accountList := make([]Account, 0, len(names))
+	for _, accountName := range names {
+		account, err := ks.describe(accountName)
+		if err != nil {
+			return nil, err
+		}
+		accountList = append(accountList, account)
+	}
+	return accountList, nil
+}
 
-	err = os.WriteFile(filePath, encryptedKey, 0644)
-	if err != nil {
-		log.Fatalf("Failed to save private key: %v", err)
+// describe reads the account from its file headers without decrypting anything
+func (ks *Keystore) describe(accountName string) (Account, error) {
+	filePath := ks.keyFile(accountName)
+	if !fileExists(filePath) {
+		filePath = ks.seedFile(accountName)
 	}
 
-	fmt.Printf("Private key successfully saved to '%s'\n", filePath)
-}
-
-func RetrievePrivateKeyHex(accountName string) (string, error) {
-	filePath := filepath.Join(AccountPath, accountName+".enc")
-	encryptedKey, err := os.ReadFile(filePath)
+	info, err := os.Stat(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return Account{}, fmt.Errorf("%w: %s", ErrAccountNotFound, accountName)
+	}
 	if err != nil {
-		return "", fmt.Errorf("failed to read private key file: %v", err)
+		return Account{}, fmt.Errorf("failed to read '%s': %v", filePath, err)
 	}
 
-	fmt.Print("Enter password: ")
-	password, err := terminal.ReadPassword(0)
+	envelope, err := ReadEnvelope(filePath)
 	if err != nil {
-		return "", fmt.Errorf("failed to read password: %v", err)
+		return Account{}, fmt.Errorf("failed to read '%s': %v", filePath, err)
 	}
-	fmt.Println()
 
-	privateKeyBytes, err := DecryptKey(encryptedKey, string(password))
-	if err != nil {
-		return "", fmt.Errorf("failed to decrypt private key: %v", err)
+	account := Account{
+		Name:    accountName,
+		Source:  envelope.Source,
+		Created: info.ModTime(),
+		Version: envelope.Version,
+	}
+	if envelope.Address != "" {
+		account.Address = common.HexToAddress(envelope.Address)
+	}
+	if envelope.Created != nil {
+		account.Created = *envelope.Created
 	}
 
-	return hex.EncodeToString(privateKeyBytes), nil
+	if ks.IsHDAccount(accountName) {
+		basePath, err := ks.DerivationPath(accountName)
+		if err != nil {
+			return Account{}, err
+		}
+		account.Path = basePath
+	}
+	return account, nil
 }
 
-// Encrypt the key using AES encryption
-func EncryptKey(key []byte, passphrase string) ([]byte, error) {
-	salt := make([]byte, 16)
-	_, err := rand.Read(salt)
+// PrivateKey decrypts the key stored as <account>.enc
+func (ks *Keystore) PrivateKey(accountName string) (*ecdsa.PrivateKey, error) {
+	privateKeyBytes, err := ks.unlock(accountName, ks.keyFile(accountName))
 	if err != nil {
 		return nil, err
 	}
 
-	dk, err := scrypt.Key([]byte(passphrase), salt, 32768, 8, 1, 32)
+	privateKey, err := crypto.ToECDSA(privateKeyBytes)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("invalid private key: %v", err)
 	}
+	return privateKey, nil
+}
 
-	block, err := aes.NewCipher(dk)
+// storePrivateKey encrypts the private key with the password and saves it as <account>.enc
+func (ks *Keystore) storePrivateKey(accountName string, privateKeyBytes []byte, password, source string) (Account, error) {
+	privateKey, err := crypto.ToECDSA(privateKeyBytes)
 	if err != nil {
-		return nil, err
+		return Account{}, fmt.Errorf("invalid private key: %v", err)
 	}
+	address := crypto.PubkeyToAddress(privateKey.PublicKey)
 
-	gcm, err := cipher.NewGCM(block)
+	metadata := newKeyMetadata(address, source)
+	encryptedKey, err := EncryptKeyWithMetadata(privateKeyBytes, password, metadata, ks.KDFParams)
 	if err != nil {
-		return nil, err
+		return Account{}, fmt.Errorf("failed to encrypt private key: %v", err)
 	}
 
-	nonce := make([]byte, gcm.NonceSize())
-	_, err = rand.Read(nonce)
-	if err != nil {
-		return nil, err
+	if err := ks.writeFile(ks.keyFile(accountName), encryptedKey); err != nil {
+		return Account{}, fmt.Errorf("failed to write private key file: %v", err)
 	}
 
-	ciphertext := gcm.Seal(nonce, nonce, key, nil)
-	return append(salt, ciphertext...), nil
+	return Account{Name: accountName, Address: address, Source: source, Created: *metadata.Created, Version: FormatVersion}, nil
 }
 
-// Decrypt the key using AES decryption
-func DecryptKey(encryptedKey []byte, passphrase string) ([]byte, error) {
-	salt := encryptedKey[:16]
-	ciphertext := encryptedKey[16:]
-
-	dk, err := scrypt.Key([]byte(passphrase), salt, 32768, 8, 1, 32)
+// unlock reads an encrypted file of the account and decrypts it with the account password
+func (ks *Keystore) unlock(accountName, filePath string) ([]byte, error) {
+	encrypted, err := os.ReadFile(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrAccountNotFound, accountName)
+	}
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to read '%s': %v", filePath, err)
 	}
 
-	block, err := aes.NewCipher(dk)
+	password, err := ks.passwords.Password(accountName)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to read password: %v", err)
 	}
 
-	gcm, err := cipher.NewGCM(block)
+	plaintext, err := DecryptKey(encrypted, password)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to decrypt '%s': %v", filePath, err)
 	}
+	return plaintext, nil
+}
 
-	nonceSize := gcm.NonceSize()
-	if len(ciphertext) < nonceSize {
-		return nil, fmt.Errorf("ciphertext too short")
+func (ks *Keystore) writeFile(filePath string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return fmt.Errorf("failed to create directories: %v", err)
 	}
+	return atomicfile.WriteFile(filePath, data, 0644)
+}
+
+func (ks *Keystore) keyFile(accountName string) string {
+	return filepath.Join(ks.dir, accountName+".enc")
+}
 
-	nonce, ciphertext := ciphertext[:nonceSize], ciphertext[nonceSize:]
-	plainText, err := gcm.Open(nil, nonce, ciphertext, nil)
+func (ks *Keystore) seedFile(accountName string) string {
+	return filepath.Join(ks.dir, accountName+".seed.enc")
+}
+
+// legacyPathFile held the base derivation path before it moved into the seed file header
+func (ks *Keystore) legacyPathFile(accountName string) string {
+	return filepath.Join(ks.dir, accountName+".path")
+}
+
+// Encrypt the key into a versioned envelope using the default scrypt parameters
+func EncryptKey(key []byte, passphrase string) ([]byte, error) {
+	return EncryptKeyWithMetadata(key, passphrase, KeyMetadata{}, DefaultScryptParams)
+}
+
+// Decrypt a key file of any format version
+func DecryptKey(encryptedKey []byte, passphrase string) ([]byte, error) {
+	envelope, err := ParseEnvelope(encryptedKey)
 	if err != nil {
 		return nil, err
 	}
+	return envelope.Decrypt(passphrase)
+}
 
-	return plainText, nil
+func fileExists(filePath string) bool {
+	_, err := os.Stat(filePath)
+	return err == nil
 }
@@ -0,0 +1,130 @@
+// keygen/keygen_test.go
+
+package keygen
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const testMnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+// testKDFParams are cheap parameters that keep the tests fast; the formats and code paths are the same
+var testKDFParams = KDFParams{Name: KDFScrypt, N: 1 << 10, R: 8, P: 1, KeyLen: 32}
+
+func newTestKeystore(t *testing.T) *Keystore {
+	t.Helper()
+	return openTestKeystore(t.TempDir(), StaticPassword("correct horse"))
+}
+
+func openTestKeystore(dir string, passwords PasswordProvider) *Keystore {
+	ks := NewKeystore(dir, passwords)
+	ks.KDFParams = testKDFParams
+	return ks
+}
+
+func TestCreateRefusesExistingAccount(t *testing.T) {
+	ks := newTestKeystore(t)
+	first, err := ks.CreateAccount("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, _ := crypto.GenerateKey()
+	attempts := map[string]func() error{
+		"create":   func() error { _, err := ks.CreateAccount("alice"); return err },
+		"import":   func() error { _, err := ks.ImportPrivateKey("alice", crypto.FromECDSA(key)); return err },
+		"mnemonic": func() error { _, err := ks.CreateMnemonicAccount("alice", testMnemonic, ""); return err },
+		"hd":       func() error { _, err := ks.CreateHDAccount("alice", testMnemonic, "", DefaultHDBasePath); return err },
+	}
+	for name, attempt := range attempts {
+		if err := attempt(); !errors.Is(err, ErrAccountExists) {
+			t.Errorf("%s: err = %v, want ErrAccountExists", name, err)
+		}
+	}
+
+	privateKey, err := ks.PrivateKey("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if crypto.PubkeyToAddress(privateKey.PublicKey) != first.Address {
+		t.Error("the original key was replaced")
+	}
+	if ks.IsHDAccount("alice") {
+		t.Error("a seed was added to the existing account")
+	}
+}
+
+func TestOverwriteReplacesWholeAccount(t *testing.T) {
+	ks := newTestKeystore(t)
+	if _, err := ks.CreateAccount("alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	ks.Overwrite = true
+	hd, err := ks.CreateHDAccount("alice", testMnemonic, "", DefaultHDBasePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fileExists(ks.keyFile("alice")) {
+		t.Error("the replaced key file was left next to the new seed")
+	}
+
+	accounts, err := ks.ListAccounts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accounts) != 1 || accounts[0].Address != hd.Address {
+		t.Errorf("accounts = %+v, want only the HD account %s", accounts, hd.Address.Hex())
+	}
+
+	imported, err := ks.CreateAccount("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ks.IsHDAccount("alice") {
+		t.Error("the replaced seed was left next to the new key")
+	}
+	if account, err := ks.Account("alice"); err != nil || account.Address != imported.Address {
+		t.Errorf("account = %+v, %v; want %s", account, err, imported.Address.Hex())
+	}
+}
+
+// noPassword fails the test if a password is asked for
+type noPassword struct{ t *testing.T }
+
+func (p noPassword) Password(accountName string) (string, error) {
+	p.t.Errorf("password requested for %s", accountName)
+	return "", errors.New("no password")
+}
+
+func (p noPassword) NewPassword(accountName string) (string, error) { return p.Password(accountName) }
+
+func TestListAccountsNeedsNoPassword(t *testing.T) {
+	dir := t.TempDir()
+	ks := openTestKeystore(dir, StaticPassword("correct horse"))
+	bob, err := ks.CreateAccount("bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice, err := ks.CreateHDAccount("alice", testMnemonic, "", DefaultHDBasePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	accounts, err := openTestKeystore(dir, noPassword{t}).ListAccounts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accounts) != 2 {
+		t.Fatalf("accounts = %+v, want alice and bob", accounts)
+	}
+	if accounts[0].Name != "alice" || accounts[0].Address != alice.Address || accounts[0].Path.String() != DefaultHDBasePath.String() {
+		t.Errorf("accounts[0] = %+v, want %+v", accounts[0], alice)
+	}
+	if accounts[1].Name != "bob" || accounts[1].Address != bob.Address || accounts[1].Source != SourceGenerated {
+		t.Errorf("accounts[1] = %+v, want %+v", accounts[1], bob)
+	}
+}
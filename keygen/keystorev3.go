@@ -0,0 +1,69 @@
+// keygen/keystorev3.go
+
+package keygen
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+)
+
+// ExportKeystoreV3 returns the account as Web3 Secret Storage (keystore v3) JSON
+// encrypted with exportPassword
+func (ks *Keystore) ExportKeystoreV3(accountName, exportPassword string) ([]byte, error) {
+	privateKey, err := ks.PrivateKey(accountName)
+	if err != nil {
+		return nil, err
+	}
+
+	keyJSON, err := EncodeKeystoreV3(crypto.FromECDSA(privateKey), exportPassword)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode keystore file: %v", err)
+	}
+	return keyJSON, nil
+}
+
+// ImportKeystoreV3 saves the key from keystore v3 JSON (geth, MetaMask, MyEtherWallet) as <account>.enc
+func (ks *Keystore) ImportKeystoreV3(accountName string, keyJSON []byte, keystorePassword string) (Account, error) {
+	if err := ks.checkNewAccount(accountName); err != nil {
+		return Account{}, err
+	}
+
+	privateKeyBytes, err := DecodeKeystoreV3(keyJSON, keystorePassword)
+	if err != nil {
+		return Account{}, fmt.Errorf("failed to decode keystore file: %v", err)
+	}
+
+	return ks.ImportPrivateKey(accountName, privateKeyBytes)
+}
+
+// EncodeKeystoreV3 encrypts a raw private key into keystore v3 JSON using the standard scrypt parameters
+func EncodeKeystoreV3(privateKeyBytes []byte, password string) ([]byte, error) {
+	privateKey, err := crypto.ToECDSA(privateKeyBytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %v", err)
+	}
+
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key id: %v", err)
+	}
+
+	key := &keystore.Key{
+		Id:         id,
+		Address:    crypto.PubkeyToAddress(privateKey.PublicKey),
+		PrivateKey: privateKey,
+	}
+	return keystore.EncryptKey(key, password, keystore.StandardScryptN, keystore.StandardScryptP)
+}
+
+// DecodeKeystoreV3 decrypts keystore JSON and returns the raw private key
+func DecodeKeystoreV3(keyJSON []byte, password string) ([]byte, error) {
+	key, err := keystore.DecryptKey(keyJSON, password)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.FromECDSA(key.PrivateKey), nil
+}
@@ -0,0 +1,49 @@
+// keygen/keystorev3_test.go
+
+package keygen
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestKeystoreV3RoundTrip(t *testing.T) {
+	ks := newTestKeystore(t)
+	original, err := ks.CreateAccount("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyJSON, err := ks.ExportKeystoreV3("alice", "export password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other := newTestKeystore(t)
+	if _, err := other.ImportKeystoreV3("bob", keyJSON, "wrong"); err == nil {
+		t.Fatal("imported with the wrong keystore password")
+	}
+	if _, err := other.Account("bob"); !errors.Is(err, ErrAccountNotFound) {
+		t.Errorf("failed import left an account behind: %v", err)
+	}
+
+	imported, err := other.ImportKeystoreV3("bob", keyJSON, "export password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported.Address != original.Address || imported.Source != SourceImported {
+		t.Errorf("imported = %+v, want address %s", imported, original.Address.Hex())
+	}
+
+	originalKey, err := ks.PrivateKey("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	importedKey, err := other.PrivateKey("bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !importedKey.Equal(originalKey) {
+		t.Error("round trip changed the key")
+	}
+}
@@ -0,0 +1,185 @@
+// keygen/migrate.go
+
+package keygen
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"go-ethereum-wallet/internal/atomicfile"
+)
+
+// accountFile is an encrypted file belonging to an account
+type accountFile struct {
+	path string
+	// metadata rebuilds the header of files written before it existed
+	metadata func(plaintext []byte) (KeyMetadata, error)
+}
+
+// Migrate re-encrypts every file of the account that is older than FormatVersion or uses
+// other KDF parameters than ks.KDFParams, and replaces it in place.
+// It returns the paths of the upgraded files.
+func (ks *Keystore) Migrate(accountName string) ([]string, error) {
+	files, err := ks.accountFiles(accountName)
+	if err != nil {
+		return nil, err
+	}
+
+	var outdated []accountFile
+	for _, file := range files {
+		envelope, err := ReadEnvelope(file.path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read '%s': %v", file.path, err)
+		}
+		if envelope.Version != FormatVersion || !sameKDFParams(envelope.KDFParams, ks.KDFParams) {
+			outdated = append(outdated, file)
+		}
+	}
+	if len(outdated) == 0 {
+		return nil, nil
+	}
+
+	password, err := ks.passwords.Password(accountName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read password: %v", err)
+	}
+
+	return ks.reencrypt(accountName, outdated, password, password)
+}
+
+// accountFiles returns the key and seed files that exist for the account
+func (ks *Keystore) accountFiles(accountName string) ([]accountFile, error) {
+	candidates := []accountFile{
+		{
+			path: ks.keyFile(accountName),
+			metadata: func(key []byte) (KeyMetadata, error) {
+				privateKey, err := crypto.ToECDSA(key)
+				if err != nil {
+					return KeyMetadata{}, fmt.Errorf("invalid private key: %v", err)
+				}
+				return KeyMetadata{Address: crypto.PubkeyToAddress(privateKey.PublicKey).Hex()}, nil
+			},
+		},
+		{
+			path: ks.seedFile(accountName),
+			metadata: func(seed []byte) (KeyMetadata, error) {
+				basePath, err := ks.DerivationPath(accountName)
+				if err != nil {
+					return KeyMetadata{}, err
+				}
+				addresses, err := DeriveAddresses(seed, basePath, 0, 1)
+				if err != nil {
+					return KeyMetadata{}, fmt.Errorf("failed to derive address: %v", err)
+				}
+				return KeyMetadata{Address: addresses[0].Hex(), Path: basePath.String()}, nil
+			},
+		},
+	}
+
+	var files []accountFile
+	for _, file := range candidates {
+		if fileExists(file.path) {
+			files = append(files, file)
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrAccountNotFound, accountName)
+	}
+	return files, nil
+}
+
+// replaceFile writes a re-encrypted file in place; tests swap it to simulate a failed write
+var replaceFile = atomicfile.WriteFile
+
+// reencrypt decrypts every file with oldPassword and encrypts it with newPassword and
+// ks.KDFParams. Nothing is written until all files have been decrypted; each file is then
+// replaced atomically. If a replacement fails, the files already replaced are restored from
+// their original contents, so the account never ends up with its key and seed under different
+// passwords. It returns the paths of the rewritten files.
+func (ks *Keystore) reencrypt(accountName string, files []accountFile, oldPassword, newPassword string) ([]string, error) {
+	originals := make([][]byte, len(files))
+	encrypted := make([][]byte, len(files))
+	for i, file := range files {
+		data, err := os.ReadFile(file.path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read '%s': %v", file.path, err)
+		}
+		originals[i] = data
+
+		envelope, err := ParseEnvelope(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read '%s': %v", file.path, err)
+		}
+
+		plaintext, err := envelope.Decrypt(oldPassword)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt '%s': %v", file.path, err)
+		}
+
+		metadata := envelope.KeyMetadata
+		if envelope.Version == FormatVersionLegacy {
+			if metadata, err = file.metadata(plaintext); err != nil {
+				return nil, err
+			}
+			// The modification time is the best record of when a legacy file was created
+			if info, err := os.Stat(file.path); err == nil {
+				created := info.ModTime().UTC().Truncate(time.Second)
+				metadata.Created = &created
+			}
+		}
+
+		encrypted[i], err = EncryptKeyWithMetadata(plaintext, newPassword, metadata, ks.KDFParams)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt '%s': %v", file.path, err)
+		}
+	}
+
+	for i, file := range files {
+		if err := replaceFile(file.path, encrypted[i], 0644); err != nil {
+			err = fmt.Errorf("failed to replace '%s': %v", file.path, err)
+			return restoreFiles(files[:i], originals, err)
+		}
+	}
+
+	var rewritten []string
+	for _, file := range files {
+		rewritten = append(rewritten, file.path)
+	}
+
+	// The derivation path now lives in the seed file header
+	if err := os.Remove(ks.legacyPathFile(accountName)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return rewritten, fmt.Errorf("failed to remove derivation path file: %v", err)
+	}
+	return rewritten, nil
+}
+
+// restoreFiles puts back the original contents of files replaced before cause. It returns
+// the paths it could not restore, which are left re-encrypted, with an error naming them.
+func restoreFiles(files []accountFile, originals [][]byte, cause error) ([]string, error) {
+	if len(files) == 0 {
+		return nil, cause
+	}
+
+	var unrestored []string
+	for i, file := range files {
+		if err := replaceFile(file.path, originals[i], 0644); err != nil {
+			unrestored = append(unrestored, file.path)
+		}
+	}
+	if len(unrestored) > 0 {
+		return unrestored, fmt.Errorf("%v; '%s' could not be restored and now uses the new password while the other files use the old one",
+			cause, strings.Join(unrestored, "', '"))
+	}
+	return nil, fmt.Errorf("%v; the other files were restored", cause)
+}
+
+// sameKDFParams compares the tuning parameters and ignores the salt
+func sameKDFParams(a, b KDFParams) bool {
+	a.Salt, b.Salt = "", ""
+	return a == b
+}
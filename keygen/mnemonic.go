@@ -0,0 +1,96 @@
+// keygen/mnemonic.go
+
+package keygen
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/tyler-smith/go-bip39"
+)
+
+// CreateMnemonicAccount saves the key at DefaultHDBasePath/0 as <account>.enc and
+// the encrypted seed next to it as <account>.seed.enc
+func (ks *Keystore) CreateMnemonicAccount(accountName, mnemonic, passphrase string) (Account, error) {
+	if err := ks.checkNewAccount(accountName); err != nil {
+		return Account{}, err
+	}
+
+	seed, err := SeedFromMnemonic(mnemonic, passphrase)
+	if err != nil {
+		return Account{}, err
+	}
+
+	privateKeyBytes, err := DerivePrivateKey(seed, ChildPath(DefaultHDBasePath, 0))
+	if err != nil {
+		return Account{}, fmt.Errorf("failed to derive private key: %v", err)
+	}
+
+	password, err := ks.passwords.NewPassword(accountName)
+	if err != nil {
+		return Account{}, fmt.Errorf("failed to read password: %v", err)
+	}
+
+	account, err := ks.storePrivateKey(accountName, privateKeyBytes, password, SourceDerived)
+	if err != nil {
+		return Account{}, err
+	}
+
+	if err := ks.storeSeed(accountName, seed, DefaultHDBasePath, password); err != nil {
+		return Account{}, err
+	}
+	if err := ks.removeReplacedFiles(accountName, ks.keyFile(accountName), ks.seedFile(accountName)); err != nil {
+		return Account{}, err
+	}
+
+	account.Path = DefaultHDBasePath
+	return account, nil
+}
+
+// NewMnemonic generates a random BIP-39 mnemonic of 12 or 24 words
+func NewMnemonic(words int) (string, error) {
+	var bitSize int
+	switch words {
+	case 12:
+		bitSize = 128
+	case 24:
+		bitSize = 256
+	default:
+		return "", fmt.Errorf("unsupported mnemonic length %d, use 12 or 24 words", words)
+	}
+
+	entropy, err := bip39.NewEntropy(bitSize)
+	if err != nil {
+		return "", err
+	}
+	return bip39.NewMnemonic(entropy)
+}
+
+// SeedFromMnemonic validates the mnemonic and returns its BIP-39 seed
+func SeedFromMnemonic(mnemonic, passphrase string) ([]byte, error) {
+	if !bip39.IsMnemonicValid(mnemonic) {
+		return nil, fmt.Errorf("invalid mnemonic")
+	}
+	return bip39.NewSeed(mnemonic, passphrase), nil
+}
+
+// storeSeed encrypts the seed with the password and saves it as <account>.seed.enc.
+// The base derivation path and its first address are recorded in the file header.
+func (ks *Keystore) storeSeed(accountName string, seed []byte, basePath accounts.DerivationPath, password string) error {
+	addresses, err := DeriveAddresses(seed, basePath, 0, 1)
+	if err != nil {
+		return fmt.Errorf("failed to derive address: %v", err)
+	}
+
+	metadata := newKeyMetadata(addresses[0], SourceDerived)
+	metadata.Path = basePath.String()
+	encryptedSeed, err := EncryptKeyWithMetadata(seed, password, metadata, ks.KDFParams)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt seed: %v", err)
+	}
+
+	if err := ks.writeFile(ks.seedFile(accountName), encryptedSeed); err != nil {
+		return fmt.Errorf("failed to write seed file: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+// keygen/password.go
+
+package keygen
+
+import "fmt"
+
+// ChangePassword decrypts the account's key and seed files with the current password and
+// atomically replaces them with copies encrypted with the new one. The key is never returned.
+func (ks *Keystore) ChangePassword(accountName string) ([]string, error) {
+	files, err := ks.accountFiles(accountName)
+	if err != nil {
+		return nil, err
+	}
+
+	oldPassword, err := ks.passwords.Password(accountName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read password: %v", err)
+	}
+
+	newPassword, err := ks.passwords.NewPassword(accountName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read new password: %v", err)
+	}
+	if newPassword == "" {
+		return nil, fmt.Errorf("new password must not be empty")
+	}
+
+	return ks.reencrypt(accountName, files, oldPassword, newPassword)
+}
@@ -0,0 +1,133 @@
+// keygen/password_test.go
+
+package keygen
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// changingPassword answers the current and the new password separately
+type changingPassword struct {
+	current, next string
+}
+
+func (p changingPassword) Password(accountName string) (string, error) { return p.current, nil }
+
+func (p changingPassword) NewPassword(accountName string) (string, error) { return p.next, nil }
+
+func TestChangePassword(t *testing.T) {
+	dir := t.TempDir()
+	ks := openTestKeystore(dir, StaticPassword("old"))
+	account, err := ks.CreateMnemonicAccount("alice", testMnemonic, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changed, err := openTestKeystore(dir, changingPassword{current: "old", next: "new"}).ChangePassword("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changed) != 2 {
+		t.Errorf("changed = %v, want the key and seed files", changed)
+	}
+
+	if _, err := ks.PrivateKey("alice"); err == nil {
+		t.Error("the old password still decrypts the key")
+	}
+	renewed := openTestKeystore(dir, StaticPassword("new"))
+	privateKey, err := renewed.DerivedPrivateKey("alice", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if crypto.PubkeyToAddress(privateKey.PublicKey) != account.Address {
+		t.Error("the new password decrypts a different seed")
+	}
+}
+
+func TestChangePasswordWrongOldPasswordLeavesFilesUntouched(t *testing.T) {
+	dir := t.TempDir()
+	ks := openTestKeystore(dir, StaticPassword("old"))
+	if _, err := ks.CreateMnemonicAccount("alice", testMnemonic, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	paths := []string{ks.keyFile("alice"), ks.seedFile("alice")}
+	before := make([][]byte, len(paths))
+	for i, path := range paths {
+		var err error
+		if before[i], err = os.ReadFile(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	changed, err := openTestKeystore(dir, changingPassword{current: "wrong", next: "new"}).ChangePassword("alice")
+	if err == nil {
+		t.Fatal("changed the password without the current one")
+	}
+	if len(changed) != 0 {
+		t.Errorf("changed = %v, want nothing", changed)
+	}
+
+	for i, path := range paths {
+		after, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(after, before[i]) {
+			t.Errorf("%s was modified", path)
+		}
+	}
+	if _, err := ks.PrivateKey("alice"); err != nil {
+		t.Errorf("the old password no longer works: %v", err)
+	}
+}
+
+func TestChangePasswordRollsBackFailedReplace(t *testing.T) {
+	dir := t.TempDir()
+	ks := openTestKeystore(dir, StaticPassword("old"))
+	if _, err := ks.CreateMnemonicAccount("alice", testMnemonic, ""); err != nil {
+		t.Fatal(err)
+	}
+	keyBefore, err := os.ReadFile(ks.keyFile("alice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The key file is replaced first; the seed file then fails
+	defer func(original func(string, []byte, os.FileMode) error) { replaceFile = original }(replaceFile)
+	replace := replaceFile
+	replaceFile = func(path string, data []byte, perm os.FileMode) error {
+		if path == ks.seedFile("alice") {
+			return errors.New("disk full")
+		}
+		return replace(path, data, perm)
+	}
+
+	changed, err := openTestKeystore(dir, changingPassword{current: "old", next: "new"}).ChangePassword("alice")
+	if err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Fatalf("err = %v, want the failed replace", err)
+	}
+	if len(changed) != 0 {
+		t.Errorf("changed = %v, want nothing after the rollback", changed)
+	}
+
+	keyAfter, err := os.ReadFile(ks.keyFile("alice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(keyAfter, keyBefore) {
+		t.Error("the key file was not restored")
+	}
+	if _, err := ks.DerivedPrivateKey("alice", 0); err != nil {
+		t.Errorf("the old password no longer opens the seed: %v", err)
+	}
+	if _, err := ks.PrivateKey("alice"); err != nil {
+		t.Errorf("the old password no longer opens the key: %v", err)
+	}
+}
@@ -0,0 +1,44 @@
+// keygen/terminal.go
+
+package keygen
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// TerminalPasswordProvider prompts for passwords on the terminal without echoing them
+type TerminalPasswordProvider struct{}
+
+func (TerminalPasswordProvider) Password(accountName string) (string, error) {
+	return ReadPassword("Enter password: ")
+}
+
+func (TerminalPasswordProvider) NewPassword(accountName string) (string, error) {
+	password, err := ReadPassword(fmt.Sprintf("Enter a password to encrypt account '%s': ", accountName))
+	if err != nil {
+		return "", err
+	}
+
+	repeated, err := ReadPassword("Repeat the password: ")
+	if err != nil {
+		return "", err
+	}
+	if password != repeated {
+		return "", fmt.Errorf("passwords do not match")
+	}
+	return password, nil
+}
+
+// ReadPassword prints the prompt and reads a line from the terminal without echo
+func ReadPassword(prompt string) (string, error) {
+	fmt.Print(prompt)
+	password, err := terminal.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Println()
+	if err != nil {
+		return "", err
+	}
+	return string(password), nil
+}
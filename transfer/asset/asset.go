@@ -3,23 +3,108 @@
 package asset
 
 import (
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
-	"math/big"
 )
 
 type Asset interface {
 	Name() string
+	Symbol() string
+	// Decimals is the number of decimals between the asset's base unit and one whole unit
+	Decimals() uint8
+	// Units maps each upper-case unit an amount may be written in to its decimals over the base unit
+	Units() map[string]uint8
+	// ABI is the contract ABI used to decode revert reasons, or nil for the native asset
+	ABI() *abi.ABI
 	CreateTransferTransaction(client *ethclient.Client, input *TransferInput) (*types.Transaction, error)
 }
 
-// TransferInput encapsulates the input parameters for creating a transfer transaction
+// TransferInput encapsulates the input parameters for creating a transfer transaction.
+// Amount is in the asset's base units (wei or token units).
+// AssetPrice and EthPrice are the fiat prices of one whole unit of the asset and of ETH;
+// they are only used to value the transfer and its fee.
+// GasTipCap and GasFeeCap select an EIP-1559 transaction; GasPrice is used otherwise.
 type TransferInput struct {
-	From     string
-	To       string
-	Amount   float64
-	EthPrice float64
-	Nonce    uint64
-	GasLimit uint64
-	GasPrice *big.Int
+	From       string
+	To         string
+	Amount     *big.Int
+	AssetPrice float64
+	EthPrice   float64
+	ChainID    *big.Int
+	Nonce      uint64
+	GasLimit   uint64
+	GasPrice   *big.Int
+	GasTipCap  *big.Int
+	GasFeeCap  *big.Int
+}
+
+// IsDynamicFee reports whether the input carries EIP-1559 fee caps
+func (input *TransferInput) IsDynamicFee() bool {
+	return input.GasTipCap != nil && input.GasFeeCap != nil
+}
+
+// MaxFee is the most the transaction can cost in wei: the gas limit at the fee cap or gas price
+func (input *TransferInput) MaxFee() *big.Int {
+	price := input.GasPrice
+	if input.IsDynamicFee() {
+		price = input.GasFeeCap
+	}
+	if price == nil {
+		return new(big.Int)
+	}
+	return new(big.Int).Mul(price, new(big.Int).SetUint64(input.GasLimit))
+}
+
+// AmountValue is the fiat value of Amount for an asset with the given decimals
+func (input *TransferInput) AmountValue(decimals uint8) (*big.Rat, error) {
+	return UnitsToFiat(input.Amount, input.AssetPrice, decimals)
+}
+
+// FeeValue is the fiat value of MaxFee
+func (input *TransferInput) FeeValue() (*big.Rat, error) {
+	return UnitsToFiat(input.MaxFee(), input.EthPrice, EtherDecimals)
+}
+
+// validate checks the fields every asset needs
+func (input *TransferInput) validate() error {
+	if input.From == "" || input.To == "" {
+		return errors.New("from and to addresses are required")
+	}
+	if input.Amount == nil || input.Amount.Sign() <= 0 {
+		return errors.New("amount must be greater than zero")
+	}
+	if input.IsDynamicFee() && input.ChainID == nil {
+		return errors.New("chain ID is required for dynamic-fee transactions")
+	}
+	return nil
+}
+
+// newTransferTx builds a dynamic-fee transaction on London-enabled chains and a legacy one otherwise
+func newTransferTx(input *TransferInput, to common.Address, value *big.Int, gasLimit uint64, data []byte) *types.Transaction {
+	if input.IsDynamicFee() {
+		return types.NewTx(&types.DynamicFeeTx{
+			ChainID:   input.ChainID,
+			Nonce:     input.Nonce,
+			GasTipCap: input.GasTipCap,
+			GasFeeCap: input.GasFeeCap,
+			Gas:       gasLimit,
+			To:        &to,
+			Value:     value,
+			Data:      data,
+		})
+	}
+
+	return types.NewTx(&types.LegacyTx{
+		Nonce:    input.Nonce,
+		To:       &to,
+		Value:    value,
+		Gas:      gasLimit,
+		GasPrice: input.GasPrice,
+		Data:     data,
+	})
 }
@@ -0,0 +1,139 @@
+// transfer/asset/asset_test.go
+
+package asset
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	testFrom = "0x9858EfFD232B4033E47d90003D41EC34EcaEda94"
+	testTo   = "0x000000000000000000000000000000000000dEaD"
+)
+
+func TestEtherTransferValue(t *testing.T) {
+	amount, err := ParseUnits("1.000000000000000001", EtherDecimals)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := (&Ether{}).CreateTransferTransaction(nil, &TransferInput{
+		From:     testFrom,
+		To:       testTo,
+		Amount:   amount,
+		GasLimit: 21000,
+		GasPrice: big.NewInt(1),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.Value().Cmp(amount) != 0 {
+		t.Errorf("value = %s, want %s", tx.Value(), amount)
+	}
+	if *tx.To() != common.HexToAddress(testTo) {
+		t.Errorf("to = %s, want %s", tx.To().Hex(), testTo)
+	}
+}
+
+func TestEtherRejectsInvalidAmount(t *testing.T) {
+	for _, amount := range []*big.Int{nil, big.NewInt(0), big.NewInt(-1)} {
+		_, err := (&Ether{}).CreateTransferTransaction(nil, &TransferInput{
+			From:     testFrom,
+			To:       testTo,
+			Amount:   amount,
+			GasLimit: 21000,
+			GasPrice: big.NewInt(1),
+		})
+		if err == nil {
+			t.Errorf("amount %v: expected error", amount)
+		}
+	}
+}
+
+func TestERC20TransferData(t *testing.T) {
+	token, err := NewERC20WithMetadata("0xdAC17F958D2ee523a2206206994597C13D831ec7", "Tether USD", "USDT", 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	amount, err := ParseUnits("0.29", token.Decimals())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := token.transferData(common.HexToAddress(testTo), amount)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	args, err := token.contractABI.Methods["transfer"].Inputs.Unpack(data[4:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := args[0].(common.Address); got != common.HexToAddress(testTo) {
+		t.Errorf("recipient = %s, want %s", got.Hex(), testTo)
+	}
+	if got := args[1].(*big.Int); got.Cmp(big.NewInt(290000)) != 0 {
+		t.Errorf("amount = %s, want 290000", got)
+	}
+}
+
+func TestTransferInputValues(t *testing.T) {
+	input := &TransferInput{
+		Amount:     big.NewInt(290000),
+		AssetPrice: 1,
+		EthPrice:   2000,
+		GasLimit:   21000,
+		GasPrice:   big.NewInt(50_000_000_000),
+	}
+
+	if got := input.MaxFee(); got.Cmp(big.NewInt(1_050_000_000_000_000)) != 0 {
+		t.Errorf("legacy MaxFee = %s, want 1050000000000000", got)
+	}
+
+	fee, err := input.FeeValue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := big.NewRat(21, 10); fee.Cmp(want) != 0 {
+		t.Errorf("FeeValue = %s, want 2.1", fee.FloatString(6))
+	}
+
+	amount, err := input.AmountValue(6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := big.NewRat(29, 100); amount.Cmp(want) != 0 {
+		t.Errorf("AmountValue = %s, want 0.29", amount.FloatString(6))
+	}
+
+	input.GasTipCap = big.NewInt(2_000_000_000)
+	input.GasFeeCap = big.NewInt(100_000_000_000)
+	if got := input.MaxFee(); got.Cmp(big.NewInt(2_100_000_000_000_000)) != 0 {
+		t.Errorf("dynamic MaxFee = %s, want 2100000000000000", got)
+	}
+}
+
+func TestERC20Name(t *testing.T) {
+	tests := []struct {
+		name, symbol, want string
+	}{
+		{"Tether USD", "USDT", "Tether USD"},
+		{"", "USDT", "USDT"},
+	}
+	for _, tt := range tests {
+		token, err := NewERC20WithMetadata(testTo, tt.name, tt.symbol, 6)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := token.Name(); got != tt.want {
+			t.Errorf("Name() = %q, want %q", got, tt.want)
+		}
+		if got := token.Symbol(); got != tt.symbol {
+			t.Errorf("Symbol() = %q, want %q", got, tt.symbol)
+		}
+	}
+}
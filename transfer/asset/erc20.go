@@ -0,0 +1,213 @@
+// transfer/asset/erc20.go
+
+package asset
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"go-ethereum-wallet/transfer/simulate"
+)
+
+const erc20ABI = `[
+	{"constant":true,"inputs":[],"name":"name","outputs":[{"name":"","type":"string"}],"stateMutability":"view","type":"function"},
+	{"constant":true,"inputs":[],"name":"symbol","outputs":[{"name":"","type":"string"}],"stateMutability":"view","type":"function"},
+	{"constant":true,"inputs":[],"name":"decimals","outputs":[{"name":"","type":"uint8"}],"stateMutability":"view","type":"function"},
+	{"constant":true,"inputs":[{"name":"_owner","type":"address"}],"name":"balanceOf","outputs":[{"name":"balance","type":"uint256"}],"stateMutability":"view","type":"function"},
+	{"constant":false,"inputs":[{"name":"_to","type":"address"},{"name":"_value","type":"uint256"}],"name":"transfer","outputs":[{"name":"","type":"bool"}],"payable":false,"stateMutability":"nonpayable","type":"function"},
+	{"inputs":[{"name":"sender","type":"address"},{"name":"balance","type":"uint256"},{"name":"needed","type":"uint256"}],"name":"ERC20InsufficientBalance","type":"error"},
+	{"inputs":[{"name":"sender","type":"address"}],"name":"ERC20InvalidSender","type":"error"},
+	{"inputs":[{"name":"receiver","type":"address"}],"name":"ERC20InvalidReceiver","type":"error"}
+]`
+
+// bytes32MetadataABI reads name and symbol from older tokens such as MKR that return bytes32
+const bytes32MetadataABI = `[
+	{"constant":true,"inputs":[],"name":"name","outputs":[{"name":"","type":"bytes32"}],"stateMutability":"view","type":"function"},
+	{"constant":true,"inputs":[],"name":"symbol","outputs":[{"name":"","type":"bytes32"}],"stateMutability":"view","type":"function"}
+]`
+
+// ERC20 is any token contract implementing the ERC-20 standard
+type ERC20 struct {
+	tokenContract common.Address
+	contractABI   abi.ABI
+	name          string
+	symbol        string
+	decimals      uint8
+}
+
+// NewERC20 reads the token's name, symbol and decimals from the chain
+func NewERC20(client *ethclient.Client, contractAddress string) (*ERC20, error) {
+	token, err := NewERC20WithMetadata(contractAddress, "", "", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := token.call(client, token.contractABI, "decimals", &token.decimals); err != nil {
+		return nil, err
+	}
+	if token.symbol, err = token.readString(client, "symbol"); err != nil {
+		return nil, err
+	}
+	if token.name, err = token.readString(client, "name"); err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// NewERC20WithMetadata builds the token from known metadata without querying the chain
+func NewERC20WithMetadata(contractAddress, name, symbol string, decimals uint8) (*ERC20, error) {
+	if !common.IsHexAddress(contractAddress) {
+		return nil, fmt.Errorf("invalid token contract address %q", contractAddress)
+	}
+
+	contractABI, err := abi.JSON(strings.NewReader(erc20ABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ABI: %v", err)
+	}
+
+	return &ERC20{
+		tokenContract: common.HexToAddress(contractAddress),
+		contractABI:   contractABI,
+		name:          name,
+		symbol:        symbol,
+		decimals:      decimals,
+	}, nil
+}
+
+// Name returns the token's name, e.g. "Tether USD", or its symbol when the name is unknown
+func (t *ERC20) Name() string {
+	if t.name == "" {
+		return t.symbol
+	}
+	return t.name
+}
+
+func (t *ERC20) Symbol() string {
+	return t.symbol
+}
+
+func (t *ERC20) Decimals() uint8 {
+	return t.decimals
+}
+
+func (t *ERC20) Units() map[string]uint8 {
+	return map[string]uint8{strings.ToUpper(t.symbol): t.decimals}
+}
+
+func (t *ERC20) ABI() *abi.ABI {
+	return &t.contractABI
+}
+
+func (t *ERC20) Address() common.Address {
+	return t.tokenContract
+}
+
+func (t *ERC20) CreateTransferTransaction(client *ethclient.Client, input *TransferInput) (*types.Transaction, error) {
+	if err := input.validate(); err != nil {
+		return nil, err
+	}
+
+	fromAddress := common.HexToAddress(input.From)
+	tokenAddress := t.tokenContract
+
+	data, err := t.transferData(common.HexToAddress(input.To), input.Amount)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := ethereum.CallMsg{
+		From:      fromAddress,
+		To:        &tokenAddress,
+		GasTipCap: input.GasTipCap,
+		GasFeeCap: input.GasFeeCap,
+		Data:      data,
+	}
+	gasLimit, err := client.EstimateGas(context.Background(), msg)
+	if err != nil {
+		// Estimation fails when the transfer would revert; simulate it to explain why
+		if simErr := simulate.Call(context.Background(), client, msg, &t.contractABI); simErr != nil {
+			return nil, simErr
+		}
+		return nil, fmt.Errorf("failed to estimate gas limit: %v", err)
+	}
+
+	return newTransferTx(input, tokenAddress, big.NewInt(0), gasLimit, data), nil
+}
+
+// BalanceOf returns the token balance of owner in base units
+func (t *ERC20) BalanceOf(ctx context.Context, caller ethereum.ContractCaller, owner common.Address) (*big.Int, error) {
+	data, err := t.contractABI.Pack("balanceOf", owner)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack balanceOf call: %v", err)
+	}
+
+	result, err := caller.CallContract(ctx, ethereum.CallMsg{To: &t.tokenContract, Data: data}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s balance of %s: %v", t.symbol, owner.Hex(), err)
+	}
+
+	var balance *big.Int
+	if err := t.contractABI.UnpackIntoInterface(&balance, "balanceOf", result); err != nil {
+		return nil, fmt.Errorf("failed to unpack balanceOf result: %v", err)
+	}
+	return balance, nil
+}
+
+// transferData packs a transfer(to, amount) call with amount in token base units
+func (t *ERC20) transferData(to common.Address, amount *big.Int) ([]byte, error) {
+	data, err := t.contractABI.Pack("transfer", to, amount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack transfer data: %v", err)
+	}
+	return data, nil
+}
+
+// call invokes a constant method of the token and unpacks its single return value into out
+func (t *ERC20) call(client *ethclient.Client, contractABI abi.ABI, method string, out interface{}) error {
+	data, err := contractABI.Pack(method)
+	if err != nil {
+		return fmt.Errorf("failed to pack %s call: %v", method, err)
+	}
+
+	result, err := client.CallContract(context.Background(), ethereum.CallMsg{To: &t.tokenContract, Data: data}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to call %s on %s: %v", method, t.tokenContract.Hex(), err)
+	}
+	if len(result) == 0 {
+		return fmt.Errorf("%s is not an ERC-20 contract: %s returned no data", t.tokenContract.Hex(), method)
+	}
+
+	if err := contractABI.UnpackIntoInterface(out, method, result); err != nil {
+		return fmt.Errorf("failed to unpack %s result: %v", method, err)
+	}
+	return nil
+}
+
+// readString reads a string metadata method, falling back to the bytes32 variant
+func (t *ERC20) readString(client *ethclient.Client, method string) (string, error) {
+	var value string
+	err := t.call(client, t.contractABI, method, &value)
+	if err == nil {
+		return value, nil
+	}
+
+	bytes32ABI, abiErr := abi.JSON(strings.NewReader(bytes32MetadataABI))
+	if abiErr != nil {
+		return "", fmt.Errorf("failed to parse ABI: %v", abiErr)
+	}
+
+	var raw [32]byte
+	if fallbackErr := t.call(client, bytes32ABI, method, &raw); fallbackErr != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(raw[:]), "\x00"), nil
+}
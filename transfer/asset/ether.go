@@ -3,44 +3,47 @@
 package asset
 
 import (
-	"errors"
-	"math/big"
-
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
 )
 
+// etherUnits are the denominations an Ether amount may be written in
+var etherUnits = map[string]uint8{
+	"ETH":  EtherDecimals,
+	"GWEI": 9,
+	"WEI":  0,
+}
+
 type Ether struct{}
 
 func (e *Ether) Name() string {
 	return "Ether"
 }
 
+func (e *Ether) Symbol() string {
+	return "ETH"
+}
+
+func (e *Ether) Decimals() uint8 {
+	return EtherDecimals
+}
+
+func (e *Ether) Units() map[string]uint8 {
+	return etherUnits
+}
+
+func (e *Ether) ABI() *abi.ABI {
+	return nil
+}
+
 func (e *Ether) CreateTransferTransaction(client *ethclient.Client, input *TransferInput) (*types.Transaction, error) {
-	if input.From == "" || input.To == "" {
-		return nil, errors.New("from and to addresses are required")
-	}
-	if input.Amount <= 0 {
-		return nil, errors.New("amount must be greater than zero")
+	if err := input.validate(); err != nil {
+		return nil, err
 	}
 
 	toAddress := common.HexToAddress(input.To)
 
-	// Convert amount from USD to Wei
-	amountInWei := new(big.Float).Mul(big.NewFloat(input.Amount), big.NewFloat(1e18))
-	amountInWei.Quo(amountInWei, big.NewFloat(input.EthPrice))
-	amountBigInt := new(big.Int)
-	amountInWei.Int(amountBigInt)
-
-	tx := types.NewTx(&types.LegacyTx{
-		Nonce:    input.Nonce,
-		To:       &toAddress,
-		Value:    amountBigInt,
-		Gas:      input.GasLimit,
-		GasPrice: input.GasPrice,
-		Data:     nil,
-	})
-
-	return tx, nil
+	return newTransferTx(input, toAddress, input.Amount, input.GasLimit, nil), nil
 }
@@ -0,0 +1,164 @@
+// transfer/asset/units.go
+
+package asset
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// EtherDecimals is the number of decimals between wei and ether
+const EtherDecimals = 18
+
+// ParseAmount converts an amount written in one of the asset's units, or in the fiat currency,
+// into base units. The price is the fiat value of one whole unit and is only used for fiat amounts.
+func ParseAmount(amount, unit string, a Asset, price float64, currency string) (*big.Int, error) {
+	unit = strings.ToUpper(strings.TrimSpace(unit))
+	if unit == "" {
+		return nil, fmt.Errorf("amount needs a unit, one of %s", strings.Join(UnitNames(a, currency), ", "))
+	}
+
+	if unit == strings.ToUpper(currency) {
+		return FiatToUnits(amount, price, a.Decimals())
+	}
+
+	decimals, ok := a.Units()[unit]
+	if !ok {
+		return nil, fmt.Errorf("unknown unit %q for %s, expected one of %s", unit, a.Name(), strings.Join(UnitNames(a, currency), ", "))
+	}
+	return ParseUnits(amount, decimals)
+}
+
+// UnitNames lists the units accepted by ParseAmount, largest first, followed by the fiat currency
+func UnitNames(a Asset, currency string) []string {
+	units := a.Units()
+	names := make([]string, 0, len(units)+1)
+	for name := range units {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if units[names[i]] != units[names[j]] {
+			return units[names[i]] > units[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	return append(names, strings.ToUpper(currency))
+}
+
+// FormatFiat renders a fiat value rounded to places decimals with its currency code, e.g. "12.34 EUR"
+func FormatFiat(value *big.Rat, currency string, places int) string {
+	return value.FloatString(places) + " " + strings.ToUpper(currency)
+}
+
+// ParseUnits converts a human decimal such as "0.29" into base units (wei or token units)
+// without rounding. It fails when the amount has more fractional digits than decimals.
+func ParseUnits(amount string, decimals uint8) (*big.Int, error) {
+	amount = strings.TrimSpace(amount)
+	if amount == "" {
+		return nil, errors.New("amount is empty")
+	}
+
+	negative := strings.HasPrefix(amount, "-")
+	digits := strings.TrimPrefix(strings.TrimPrefix(amount, "-"), "+")
+
+	whole, fraction, _ := strings.Cut(digits, ".")
+	if whole == "" && fraction == "" {
+		return nil, fmt.Errorf("invalid amount %q", amount)
+	}
+	if !isDigits(whole) || !isDigits(fraction) {
+		return nil, fmt.Errorf("invalid amount %q", amount)
+	}
+
+	fraction = strings.TrimRight(fraction, "0")
+	if len(fraction) > int(decimals) {
+		return nil, fmt.Errorf("amount %q has more than %d decimal places", amount, decimals)
+	}
+
+	value, ok := new(big.Int).SetString(whole+fraction+strings.Repeat("0", int(decimals)-len(fraction)), 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid amount %q", amount)
+	}
+	if negative {
+		value.Neg(value)
+	}
+	return value, nil
+}
+
+// FormatUnits converts base units into a human decimal without trailing zeros, e.g. 290000 with 6 decimals is "0.29"
+func FormatUnits(value *big.Int, decimals uint8) string {
+	digits := new(big.Int).Abs(value).String()
+	if len(digits) <= int(decimals) {
+		digits = strings.Repeat("0", int(decimals)-len(digits)+1) + digits
+	}
+
+	point := len(digits) - int(decimals)
+	whole, fraction := digits[:point], strings.TrimRight(digits[point:], "0")
+
+	result := whole
+	if fraction != "" {
+		result += "." + fraction
+	}
+	if value.Sign() < 0 {
+		result = "-" + result
+	}
+	return result
+}
+
+// FiatToUnits converts a fiat decimal amount into base units at the given price per whole unit,
+// rounding down to the nearest base unit
+func FiatToUnits(fiat string, price float64, decimals uint8) (*big.Int, error) {
+	fiatValue, ok := new(big.Rat).SetString(strings.TrimSpace(fiat))
+	if !ok {
+		return nil, fmt.Errorf("invalid amount %q", fiat)
+	}
+
+	priceValue, err := priceRat(price)
+	if err != nil {
+		return nil, err
+	}
+
+	units := new(big.Rat).Mul(fiatValue, new(big.Rat).SetInt(pow10(decimals)))
+	units.Quo(units, priceValue)
+	return new(big.Int).Quo(units.Num(), units.Denom()), nil
+}
+
+// UnitsToFiat converts base units into their exact fiat value at the given price per whole unit
+func UnitsToFiat(value *big.Int, price float64, decimals uint8) (*big.Rat, error) {
+	priceValue, err := priceRat(price)
+	if err != nil {
+		return nil, err
+	}
+
+	fiat := new(big.Rat).SetFrac(value, pow10(decimals))
+	return fiat.Mul(fiat, priceValue), nil
+}
+
+// priceRat reads a price as the shortest decimal that round-trips the float64,
+// so a quote of 3456.78 is used as exactly 3456.78
+func priceRat(price float64) (*big.Rat, error) {
+	if price <= 0 {
+		return nil, fmt.Errorf("price must be greater than zero")
+	}
+	value, ok := new(big.Rat).SetString(strconv.FormatFloat(price, 'f', -1, 64))
+	if !ok {
+		return nil, fmt.Errorf("invalid price %v", price)
+	}
+	return value, nil
+}
+
+func pow10(decimals uint8) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,260 @@
+// transfer/asset/units_test.go
+
+package asset
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func mustBig(t *testing.T, s string) *big.Int {
+	t.Helper()
+	value, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		t.Fatalf("bad test value %q", s)
+	}
+	return value
+}
+
+func TestParseUnits(t *testing.T) {
+	tests := []struct {
+		amount   string
+		decimals uint8
+		want     string
+	}{
+		{"0.29", 6, "290000"},
+		{"1", 18, "1000000000000000000"},
+		{"0.1", 18, "100000000000000000"},
+		{"1.000000000000000001", 18, "1000000000000000001"},
+		{".5", 6, "500000"},
+		{"5.", 6, "5000000"},
+		{"12.3400", 2, "1234"},
+		{" 42 ", 0, "42"},
+		{"+7", 0, "7"},
+		{"-1.5", 1, "-15"},
+		{"123456789012345678901234567890", 18, "123456789012345678901234567890000000000000000000"},
+	}
+	for _, tt := range tests {
+		got, err := ParseUnits(tt.amount, tt.decimals)
+		if err != nil {
+			t.Errorf("ParseUnits(%q, %d) error: %v", tt.amount, tt.decimals, err)
+			continue
+		}
+		if got.Cmp(mustBig(t, tt.want)) != 0 {
+			t.Errorf("ParseUnits(%q, %d) = %s, want %s", tt.amount, tt.decimals, got, tt.want)
+		}
+	}
+}
+
+func TestParseUnitsErrors(t *testing.T) {
+	tests := []struct {
+		amount   string
+		decimals uint8
+	}{
+		{"", 6},
+		{".", 6},
+		{"abc", 6},
+		{"1.2.3", 6},
+		{"1e6", 6},
+		{"0.1234567", 6},
+		{"1.5", 0},
+		{"--1", 6},
+	}
+	for _, tt := range tests {
+		if got, err := ParseUnits(tt.amount, tt.decimals); err == nil {
+			t.Errorf("ParseUnits(%q, %d) = %s, want error", tt.amount, tt.decimals, got)
+		}
+	}
+}
+
+func TestFormatUnits(t *testing.T) {
+	tests := []struct {
+		value    string
+		decimals uint8
+		want     string
+	}{
+		{"290000", 6, "0.29"},
+		{"1000000000000000000", 18, "1"},
+		{"1000000000000000001", 18, "1.000000000000000001"},
+		{"1", 18, "0.000000000000000001"},
+		{"0", 18, "0"},
+		{"42", 0, "42"},
+		{"-15", 1, "-1.5"},
+		{"123456789", 3, "123456.789"},
+	}
+	for _, tt := range tests {
+		if got := FormatUnits(mustBig(t, tt.value), tt.decimals); got != tt.want {
+			t.Errorf("FormatUnits(%s, %d) = %q, want %q", tt.value, tt.decimals, got, tt.want)
+		}
+	}
+}
+
+func TestParseFormatRoundTrip(t *testing.T) {
+	for _, amount := range []string{"0.29", "1", "0.000001", "1234567.891011", "0.1"} {
+		value, err := ParseUnits(amount, 6)
+		if err != nil {
+			t.Fatalf("ParseUnits(%q) error: %v", amount, err)
+		}
+		if got := FormatUnits(value, 6); got != amount {
+			t.Errorf("round trip of %q gave %q", amount, got)
+		}
+	}
+}
+
+func TestFiatToUnits(t *testing.T) {
+	tests := []struct {
+		fiat     string
+		price    float64
+		decimals uint8
+		want     string
+	}{
+		// a USD-pegged token converts without the float64 loss of int64(0.29 * 1e6) == 289999
+		{"0.29", 1, 6, "290000"},
+		{"10", 2000, 18, "5000000000000000"},
+		{"0.1", 3456.78, 18, "28928656148207"},
+		{"1", 3, 0, "0"},
+		{"100", 3, 2, "3333"},
+		{"0", 2000, 18, "0"},
+	}
+	for _, tt := range tests {
+		got, err := FiatToUnits(tt.fiat, tt.price, tt.decimals)
+		if err != nil {
+			t.Errorf("FiatToUnits(%q, %v, %d) error: %v", tt.fiat, tt.price, tt.decimals, err)
+			continue
+		}
+		if got.Cmp(mustBig(t, tt.want)) != 0 {
+			t.Errorf("FiatToUnits(%q, %v, %d) = %s, want %s", tt.fiat, tt.price, tt.decimals, got, tt.want)
+		}
+	}
+}
+
+func TestFiatToUnitsErrors(t *testing.T) {
+	if _, err := FiatToUnits("abc", 1, 6); err == nil {
+		t.Error("expected error for invalid amount")
+	}
+	if _, err := FiatToUnits("1", 0, 6); err == nil {
+		t.Error("expected error for zero price")
+	}
+	if _, err := FiatToUnits("1", -5, 6); err == nil {
+		t.Error("expected error for negative price")
+	}
+}
+
+func TestUnitsToFiat(t *testing.T) {
+	tests := []struct {
+		value    string
+		price    float64
+		decimals uint8
+		want     string
+	}{
+		{"5000000000000000", 2000, 18, "10"},
+		{"290000", 1, 6, "0.29"},
+		{"1000000000000000000", 3456.78, 18, "3456.78"},
+		{"1", 0.5, 0, "0.5"},
+	}
+	for _, tt := range tests {
+		got, err := UnitsToFiat(mustBig(t, tt.value), tt.price, tt.decimals)
+		if err != nil {
+			t.Errorf("UnitsToFiat(%s, %v, %d) error: %v", tt.value, tt.price, tt.decimals, err)
+			continue
+		}
+		want, _ := new(big.Rat).SetString(tt.want)
+		if got.Cmp(want) != 0 {
+			t.Errorf("UnitsToFiat(%s, %v, %d) = %s, want %s", tt.value, tt.price, tt.decimals, got.RatString(), tt.want)
+		}
+	}
+
+	if _, err := UnitsToFiat(big.NewInt(1), 0, 18); err == nil {
+		t.Error("expected error for zero price")
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	usdt, err := NewERC20WithMetadata("0xdAC17F958D2ee523a2206206994597C13D831ec7", "Tether USD", "USDT", 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		amount string
+		unit   string
+		asset  Asset
+		price  float64
+		want   string
+	}{
+		{"0.5", "ETH", &Ether{}, 2000, "500000000000000000"},
+		{"0.5", "eth", &Ether{}, 2000, "500000000000000000"},
+		{"30", "gwei", &Ether{}, 2000, "30000000000"},
+		{"1.5", "GWEI", &Ether{}, 2000, "1500000000"},
+		{"21000", "wei", &Ether{}, 2000, "21000"},
+		{"10", "USD", &Ether{}, 2000, "5000000000000000"},
+		{"100", "USDT", usdt, 1, "100000000"},
+		{"0.29", "usd", usdt, 1, "290000"},
+	}
+	for _, tt := range tests {
+		got, err := ParseAmount(tt.amount, tt.unit, tt.asset, tt.price, "USD")
+		if err != nil {
+			t.Errorf("ParseAmount(%q, %q) error: %v", tt.amount, tt.unit, err)
+			continue
+		}
+		if got.Cmp(mustBig(t, tt.want)) != 0 {
+			t.Errorf("ParseAmount(%q, %q) = %s, want %s", tt.amount, tt.unit, got, tt.want)
+		}
+	}
+}
+
+func TestParseAmountErrors(t *testing.T) {
+	usdt, err := NewERC20WithMetadata("0xdAC17F958D2ee523a2206206994597C13D831ec7", "Tether USD", "USDT", 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		amount string
+		unit   string
+		asset  Asset
+	}{
+		{"1", "", &Ether{}},
+		{"1", "USDT", &Ether{}},
+		{"1", "ETH", usdt},
+		{"0.5", "WEI", &Ether{}},
+		{"0.0000001", "USDT", usdt},
+		{"1", "EUR", usdt},
+	}
+	for _, tt := range tests {
+		if got, err := ParseAmount(tt.amount, tt.unit, tt.asset, 1, "USD"); err == nil {
+			t.Errorf("ParseAmount(%q, %q) = %s, want error", tt.amount, tt.unit, got)
+		}
+	}
+}
+
+func TestParseAmountCurrency(t *testing.T) {
+	got, err := ParseAmount("10", "eur", &Ether{}, 2500, "EUR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := mustBig(t, "4000000000000000"); got.Cmp(want) != 0 {
+		t.Errorf("ParseAmount(10 EUR) = %s, want %s", got, want)
+	}
+
+	if _, err := ParseAmount("10", "USD", &Ether{}, 2500, "EUR"); err == nil {
+		t.Error("expected error for a fiat unit other than the configured currency")
+	}
+}
+
+func TestUnitNames(t *testing.T) {
+	got := strings.Join(UnitNames(&Ether{}, "gbp"), ",")
+	if want := "ETH,GWEI,WEI,GBP"; got != want {
+		t.Errorf("UnitNames(Ether) = %s, want %s", got, want)
+	}
+}
+
+func TestFormatFiat(t *testing.T) {
+	if got := FormatFiat(big.NewRat(1234, 100), "eur", 2); got != "12.34 EUR" {
+		t.Errorf("FormatFiat = %q, want %q", got, "12.34 EUR")
+	}
+	if got := FormatFiat(big.NewRat(21, 10), "USD", 6); got != "2.100000 USD" {
+		t.Errorf("FormatFiat = %q, want %q", got, "2.100000 USD")
+	}
+}
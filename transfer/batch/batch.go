@@ -0,0 +1,244 @@
+// transfer/batch/batch.go
+
+package batch
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"go-ethereum-wallet/internal/atomicfile"
+	"go-ethereum-wallet/transfer/asset"
+	"go-ethereum-wallet/transfer/nonce"
+)
+
+// Result statuses. A row is signed before it is broadcast, so a run interrupted in between
+// can tell on resume which rows might already be on their way.
+const (
+	StatusSigned    = "signed"
+	StatusSent      = "sent"
+	StatusConfirmed = "confirmed"
+	StatusReverted  = "reverted"
+	StatusFailed    = "failed"
+)
+
+var paymentHeader = []string{"address", "asset", "amount", "memo"}
+var resultHeader = []string{"line", "address", "asset", "amount", "memo", "nonce", "tx_hash", "status", "error"}
+
+// Payment is one row of the payout file. Amount is a decimal in the asset's own unit,
+// optionally followed by another unit the asset accepts, e.g. "250 USD".
+type Payment struct {
+	Line    int
+	Address string
+	Asset   string
+	Amount  string
+	Memo    string
+}
+
+// Result records what happened to a payment
+type Result struct {
+	Payment
+	Nonce  string
+	TxHash string
+	Status string
+	Error  string
+}
+
+// Done reports whether the payment reached the network, so a resumed run must not send it again.
+// Reverted payments count as done: the fee was spent and retrying is a decision for the operator.
+func (r Result) Done() bool {
+	return r.Status == StatusSent || r.Status == StatusConfirmed || r.Status == StatusReverted
+}
+
+// ResumeNonce returns the nonce a row signed in an interrupted run must be sent with again.
+// The row keeps its own nonce, so the earlier signed transaction and the new one can never both
+// be mined. ok is false when the nonce is mined, or was since used by another transaction, and
+// the row takes a fresh one. mined and pending come from nonce.Manager.Reconcile.
+func (r Result) ResumeNonce(mined uint64, pending []nonce.Entry) (n uint64, ok bool) {
+	if r.Status != StatusSigned || r.Nonce == "" {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(r.Nonce, 10, 64)
+	if err != nil || n < mined {
+		return 0, false
+	}
+	for _, entry := range pending {
+		if entry.Nonce == n {
+			return n, entry.TxHash == "" || strings.EqualFold(entry.TxHash, r.TxHash)
+		}
+	}
+	return 0, false
+}
+
+// ReadPayments reads a CSV with the header address,asset,amount,memo. The memo column is optional.
+func ReadPayments(path string) ([]Payment, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open payouts: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read payouts header: %w", err)
+	}
+	for i, name := range paymentHeader[:3] {
+		if i >= len(header) || !strings.EqualFold(strings.TrimSpace(header[i]), name) {
+			return nil, fmt.Errorf("payouts header must start with %s", strings.Join(paymentHeader, ","))
+		}
+	}
+
+	var payments []Payment
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read payouts: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+
+		payment := Payment{Line: line}
+		fields := []*string{&payment.Address, &payment.Asset, &payment.Amount, &payment.Memo}
+		for i, field := range fields {
+			if i < len(record) {
+				*field = strings.TrimSpace(record[i])
+			}
+		}
+		payments = append(payments, payment)
+	}
+
+	if len(payments) == 0 {
+		return nil, fmt.Errorf("payouts file %s has no rows", path)
+	}
+	return payments, nil
+}
+
+// Validate checks the recipient address and finds the payment's asset by symbol
+func (p Payment) Validate(assets map[string]asset.Asset) (asset.Asset, error) {
+	if !common.IsHexAddress(p.Address) {
+		return nil, fmt.Errorf("invalid address %q", p.Address)
+	}
+	if common.HexToAddress(p.Address) == (common.Address{}) {
+		return nil, errors.New("recipient is the zero address")
+	}
+
+	for _, candidate := range assets {
+		if strings.EqualFold(candidate.Symbol(), p.Asset) {
+			return candidate, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown asset %q", p.Asset)
+}
+
+// ParseAmount converts the amount into base units of a. An amount without a unit is in the
+// asset's own symbol; price and currency are used for fiat amounts.
+func (p Payment) ParseAmount(a asset.Asset, price float64, currency string) (*big.Int, error) {
+	fields := strings.Fields(p.Amount)
+	unit := a.Symbol()
+	switch len(fields) {
+	case 1:
+	case 2:
+		unit = fields[1]
+	default:
+		return nil, fmt.Errorf("invalid amount %q", p.Amount)
+	}
+
+	amount, err := asset.ParseAmount(fields[0], unit, a, price, currency)
+	if err != nil {
+		return nil, err
+	}
+	if amount.Sign() <= 0 {
+		return nil, fmt.Errorf("amount %q must be greater than zero", p.Amount)
+	}
+	return amount, nil
+}
+
+// ResultsPath names the results file written next to the payouts file, e.g. "june.results.csv"
+func ResultsPath(paymentsPath string) string {
+	ext := filepath.Ext(paymentsPath)
+	return strings.TrimSuffix(paymentsPath, ext) + ".results.csv"
+}
+
+// ReadResults loads a results file from an earlier run, keyed by payout line.
+// A missing file is an empty result set.
+func ReadResults(path string) (map[int]Result, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[int]Result{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open results: %w", err)
+	}
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read results %s: %w", path, err)
+	}
+
+	results := make(map[int]Result)
+	for i, record := range records {
+		if i == 0 {
+			continue
+		}
+		if len(record) != len(resultHeader) {
+			return nil, fmt.Errorf("results %s line %d has %d columns, want %d", path, i+1, len(record), len(resultHeader))
+		}
+		line, err := strconv.Atoi(record[0])
+		if err != nil {
+			return nil, fmt.Errorf("results %s line %d: invalid payout line %q", path, i+1, record[0])
+		}
+		results[line] = Result{
+			Payment: Payment{Line: line, Address: record[1], Asset: record[2], Amount: record[3], Memo: record[4]},
+			Nonce:   record[5],
+			TxHash:  record[6],
+			Status:  record[7],
+			Error:   record[8],
+		}
+	}
+	return results, nil
+}
+
+// Matches reports whether an earlier result belongs to the same payment, so that a payout
+// file edited between runs is not resumed against the wrong rows
+func (r Result) Matches(p Payment) bool {
+	return r.Line == p.Line &&
+		strings.EqualFold(r.Address, p.Address) &&
+		strings.EqualFold(r.Asset, p.Asset) &&
+		r.Amount == p.Amount
+}
+
+// WriteResults replaces the results file in one rename, so it always holds a complete snapshot
+func WriteResults(path string, results []Result) error {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	writer.Write(resultHeader)
+	for _, r := range results {
+		writer.Write([]string{strconv.Itoa(r.Line), r.Address, r.Asset, r.Amount, r.Memo, r.Nonce, r.TxHash, r.Status, r.Error})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write results: %w", err)
+	}
+	if err := atomicfile.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		return fmt.Errorf("failed to write results: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,250 @@
+// transfer/batch/batch_test.go
+
+package batch
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"go-ethereum-wallet/transfer/asset"
+	"go-ethereum-wallet/transfer/nonce"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadPayments(t *testing.T) {
+	path := writeFile(t, "june.csv", `address,asset,amount,memo
+0x000000000000000000000000000000000000dEaD, ETH, 0.5, "June, design"
+
+0x9858EfFD232B4033E47d90003D41EC34EcaEda94,USDT,250 USD
+`)
+
+	payments, err := ReadPayments(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Payment{
+		{Line: 2, Address: "0x000000000000000000000000000000000000dEaD", Asset: "ETH", Amount: "0.5", Memo: "June, design"},
+		{Line: 4, Address: "0x9858EfFD232B4033E47d90003D41EC34EcaEda94", Asset: "USDT", Amount: "250 USD"},
+	}
+	if !reflect.DeepEqual(payments, want) {
+		t.Errorf("payments = %+v, want %+v", payments, want)
+	}
+}
+
+func TestReadPaymentsErrors(t *testing.T) {
+	for name, content := range map[string]string{
+		"bad header": "to,token,value\n0x00,ETH,1\n",
+		"no rows":    "address,asset,amount,memo\n",
+	} {
+		if _, err := ReadPayments(writeFile(t, "payouts.csv", content)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestPaymentValidateAndParse(t *testing.T) {
+	usdt, err := asset.NewERC20WithMetadata("0xdAC17F958D2ee523a2206206994597C13D831ec7", "Tether USD", "USDT", 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assets := map[string]asset.Asset{"1": &asset.Ether{}, "2": usdt}
+
+	payment := Payment{Address: "0x000000000000000000000000000000000000dEaD", Asset: "usdt", Amount: "100.25"}
+	found, err := payment.Validate(assets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found != usdt {
+		t.Errorf("asset = %s, want USDT", found.Symbol())
+	}
+	amount, err := payment.ParseAmount(found, 1, "USD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if amount.String() != "100250000" {
+		t.Errorf("amount = %s, want 100250000", amount)
+	}
+
+	payment.Amount = "10 EUR"
+	if amount, err := payment.ParseAmount(found, 0.5, "EUR"); err != nil || amount.String() != "20000000" {
+		t.Errorf("fiat amount = %v (%v), want 20000000", amount, err)
+	}
+
+	invalid := []Payment{
+		{Address: "0x1234", Asset: "ETH", Amount: "1"},
+		{Address: "0x0000000000000000000000000000000000000000", Asset: "ETH", Amount: "1"},
+		{Address: "0x000000000000000000000000000000000000dEaD", Asset: "DOGE", Amount: "1"},
+	}
+	for _, p := range invalid {
+		if _, err := p.Validate(assets); err == nil {
+			t.Errorf("%+v: expected validation error", p)
+		}
+	}
+
+	for _, text := range []string{"0", "-1", "1 2 3", "abc"} {
+		p := Payment{Amount: text}
+		if _, err := p.ParseAmount(&asset.Ether{}, 2000, "USD"); err == nil {
+			t.Errorf("amount %q: expected error", text)
+		}
+	}
+}
+
+func TestResultsRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "june.results.csv")
+	results := []Result{
+		{Payment: Payment{Line: 2, Address: "0xdead", Asset: "ETH", Amount: "0.5", Memo: "June, design"}, Nonce: "7", TxHash: "0xabc", Status: StatusSent},
+		{Payment: Payment{Line: 3, Address: "0xbeef", Asset: "USDT", Amount: "250"}, Status: StatusFailed, Error: "insufficient funds"},
+	}
+	if err := WriteResults(path, results); err != nil {
+		t.Fatal(err)
+	}
+
+	read, err := ReadResults(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range results {
+		if !reflect.DeepEqual(read[r.Line], r) {
+			t.Errorf("line %d = %+v, want %+v", r.Line, read[r.Line], r)
+		}
+	}
+
+	if !read[2].Done() || read[3].Done() {
+		t.Error("sent rows should be done and failed rows retried")
+	}
+	if !read[2].Matches(results[0].Payment) {
+		t.Error("result should match its payment")
+	}
+	edited := results[0].Payment
+	edited.Amount = "5"
+	if read[2].Matches(edited) {
+		t.Error("result should not match an edited payment")
+	}
+
+	missing, err := ReadResults(filepath.Join(t.TempDir(), "none.csv"))
+	if err != nil || len(missing) != 0 {
+		t.Errorf("missing results file = %v, %v; want empty", missing, err)
+	}
+}
+
+func TestResultsPath(t *testing.T) {
+	if got := ResultsPath("payouts/june.csv"); got != "payouts/june.results.csv" {
+		t.Errorf("ResultsPath = %s", got)
+	}
+}
+
+type fakeBackend struct {
+	mined, pending uint64
+}
+
+func (f *fakeBackend) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	return f.mined, nil
+}
+
+func (f *fakeBackend) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	return f.pending, nil
+}
+
+func (f *fakeBackend) TransactionByHash(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+	return nil, false, ethereum.NotFound
+}
+
+func TestResumeAfterCrashBeforeBroadcast(t *testing.T) {
+	account := common.HexToAddress("0x9858EfFD232B4033E47d90003D41EC34EcaEda94")
+	backend := &fakeBackend{mined: 5, pending: 5}
+	nonces := nonce.NewManager(t.TempDir(), backend, big.NewInt(11155111))
+	resultsPath := filepath.Join(t.TempDir(), "june.results.csv")
+
+	// The first run takes nonce 5, signs the row and crashes before broadcasting or recording it
+	n, err := nonces.Next(context.Background(), account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := Result{Payment: Payment{Line: 2, Address: "0xdead", Asset: "ETH", Amount: "0.5"}, Nonce: strconv.FormatUint(n, 10), TxHash: "0xabc", Status: StatusSigned}
+	if err := WriteResults(resultsPath, []Result{signed}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The resumed run finds the row unsent and must pay it again with the same nonce
+	previous, err := ReadResults(resultsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reconciliation, err := nonces.Reconcile(context.Background(), account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reconciliation.Gaps) != 0 {
+		t.Errorf("gaps = %v, want none right after the crash", reconciliation.Gaps)
+	}
+	resumed, ok := previous[2].ResumeNonce(reconciliation.Mined, reconciliation.Pending)
+	if !ok || resumed != n {
+		t.Fatalf("ResumeNonce = %d, %v; want %d, true", resumed, ok, n)
+	}
+	if err := nonces.Assign(account, resumed); err != nil {
+		t.Fatal(err)
+	}
+	if next, _ := nonces.Next(context.Background(), account); next != n+1 {
+		t.Errorf("next fresh nonce = %d, want %d", next, n+1)
+	}
+
+	// Once the nonce is mined, or used by another transaction, the row needs a fresh nonce
+	if _, ok := previous[2].ResumeNonce(n+1, reconciliation.Pending); ok {
+		t.Error("a mined nonce must not be reused")
+	}
+	other := []nonce.Entry{{Nonce: n, TxHash: "0xcancel"}}
+	if _, ok := previous[2].ResumeNonce(reconciliation.Mined, other); ok {
+		t.Error("a nonce taken by another transaction must not be reused")
+	}
+	failed := previous[2]
+	failed.Status = StatusFailed
+	if _, ok := failed.ResumeNonce(reconciliation.Mined, reconciliation.Pending); ok {
+		t.Error("only rows left signed keep their nonce")
+	}
+}
+
+func TestResumeAfterUnknownBroadcast(t *testing.T) {
+	account := common.HexToAddress("0x9858EfFD232B4033E47d90003D41EC34EcaEda94")
+	backend := &fakeBackend{mined: 5, pending: 5}
+	nonces := nonce.NewManager(t.TempDir(), backend, big.NewInt(11155111))
+
+	// The broadcast timed out, so the row stays signed and the nonce stays recorded for its transaction
+	n, err := nonces.Next(context.Background(), account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := types.NewTx(&types.DynamicFeeTx{ChainID: big.NewInt(11155111), Nonce: n, Gas: 21000, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(2)})
+	if err := nonces.Record(account, tx); err != nil {
+		t.Fatal(err)
+	}
+	row := Result{Payment: Payment{Line: 2, Address: "0xdead", Asset: "ETH", Amount: "0.5"}, Nonce: strconv.FormatUint(n, 10), TxHash: tx.Hash().Hex(), Status: StatusSigned}
+
+	reconciliation, err := nonces.Reconcile(context.Background(), account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resumed, ok := row.ResumeNonce(reconciliation.Mined, reconciliation.Pending); !ok || resumed != n {
+		t.Fatalf("ResumeNonce = %d, %v; want %d, true", resumed, ok, n)
+	}
+	if next, _ := nonces.Next(context.Background(), account); next != n+1 {
+		t.Errorf("next fresh nonce = %d, want %d while the unknown broadcast holds %d", next, n+1, n)
+	}
+}
@@ -1,19 +1,109 @@
 package config
 
+import "time"
+
+// Config describes a network. ChainID is the chain the RPC node at PublicNodeUrl must be on;
+// 0 skips the check. The ETH/USD price comes from PriceOracle: "median" of Coinbase and the
+// Chainlink feed at ChainlinkETHUSDFeed, or either source alone. Quotes older than MaxPriceAge
+// are ignored and a spread above MaxPriceDeviation (a fraction) between them stops the transfer.
+// Currency is the fiat code, e.g. USD, EUR or GBP, that amounts are entered and shown in.
+// GasStrategy names the gas pricing strategy to use; empty asks for one on every transfer.
+// After sending, the transfer waits for Confirmations blocks; 0 returns without waiting.
+// Nonces handed out to each account are kept under NonceStorePath.
 type Config struct {
-	PublicNodeUrl       string
-	EthereumExplorerUrl string
-	UsdtContractAddress string
+	Name                string        `json:"-"`
+	PublicNodeUrl       string        `json:"rpcUrl"`
+	EthereumExplorerUrl string        `json:"explorerUrl"`
+	ChainID             uint64        `json:"chainId"`
+	TokenRegistryPath   string        `json:"tokenRegistry"`
+	GasStrategy         string        `json:"gasStrategy"`
+	MaxGasPriceGwei     float64       `json:"maxGasPriceGwei"`
+	PriceOracle         string        `json:"priceOracle"`
+	ChainlinkETHUSDFeed string        `json:"chainlinkEthUsdFeed"`
+	MaxPriceAge         time.Duration `json:"-"`
+	MaxPriceDeviation   float64       `json:"maxPriceDeviation"`
+	Currency            string        `json:"currency"`
+	Confirmations       uint64        `json:"confirmations"`
+	NonceStorePath      string        `json:"nonceStore"`
+}
+
+// Gas pricing strategies GasStrategy may name
+const (
+	GasStrategySuggested  = "suggested"
+	GasStrategyMultiplier = "multiplier"
+	GasStrategyFeeHistory = "fee-history"
+	GasStrategyFixedCap   = "fixed-cap"
+)
+
+// ETH price oracles PriceOracle may name
+const (
+	PriceOracleMedian    = "median"
+	PriceOracleCoinbase  = "coinbase"
+	PriceOracleChainlink = "chainlink"
+)
+
+// Defaults is the starting point for custom networks defined in a config file
+var Defaults = Config{
+	MaxGasPriceGwei:   100,
+	PriceOracle:       PriceOracleCoinbase,
+	MaxPriceAge:       2 * time.Hour,
+	MaxPriceDeviation: 0.02,
+	Currency:          "USD",
+	Confirmations:     1,
+	NonceStorePath:    "./nonces",
 }
 
 var EthereumMainnet = Config{
+	Name:                "mainnet",
 	PublicNodeUrl:       "https://cloudflare-eth.com",
 	EthereumExplorerUrl: "https://etherscan.io",
-	UsdtContractAddress: "0xdAC17F958D2ee523a2206206994597C13D831ec7",
+	ChainID:             1,
+	TokenRegistryPath:   "./tokens/mainnet.json",
+	MaxGasPriceGwei:     50,
+	PriceOracle:         PriceOracleMedian,
+	ChainlinkETHUSDFeed: "0x5f4eC3Df9cbd43714FE2740f5E3616155c5b8419",
+	MaxPriceAge:         2 * time.Hour,
+	MaxPriceDeviation:   0.02,
+	Currency:            "USD",
+	Confirmations:       1,
+	NonceStorePath:      "./nonces",
 }
 
 var SepoliaTestnet = Config{
+	Name:                "sepolia",
 	PublicNodeUrl:       "https://rpc.sepolia.org",
 	EthereumExplorerUrl: "https://sepolia.etherscan.io",
-	UsdtContractAddress: "0xE3d2B274Ec5a0F4e9FA12911F76BA052faFeA6aE",
+	ChainID:             11155111,
+	TokenRegistryPath:   "./tokens/sepolia.json",
+	MaxGasPriceGwei:     100,
+	PriceOracle:         PriceOracleMedian,
+	ChainlinkETHUSDFeed: "0x694AA1769357215DE4FAC081bf1f309aDC325306",
+	MaxPriceAge:         2 * time.Hour,
+	MaxPriceDeviation:   0.02,
+	Currency:            "USD",
+	Confirmations:       1,
+	NonceStorePath:      "./nonces",
+}
+
+// HoleskyTestnet has no Chainlink ETH/USD feed, so ETH is priced at Coinbase alone
+var HoleskyTestnet = Config{
+	Name:                "holesky",
+	PublicNodeUrl:       "https://ethereum-holesky-rpc.publicnode.com",
+	EthereumExplorerUrl: "https://holesky.etherscan.io",
+	ChainID:             17000,
+	TokenRegistryPath:   "./tokens/holesky.json",
+	MaxGasPriceGwei:     100,
+	PriceOracle:         PriceOracleCoinbase,
+	MaxPriceAge:         2 * time.Hour,
+	MaxPriceDeviation:   0.02,
+	Currency:            "USD",
+	Confirmations:       1,
+	NonceStorePath:      "./nonces",
+}
+
+// Networks are the built-in profiles by name
+var Networks = map[string]Config{
+	EthereumMainnet.Name: EthereumMainnet,
+	SepoliaTestnet.Name:  SepoliaTestnet,
+	HoleskyTestnet.Name:  HoleskyTestnet,
 }
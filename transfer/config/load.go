@@ -0,0 +1,218 @@
+// transfer/config/load.go
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultPath is read when no config file is given; unlike a given file it may be missing
+	DefaultPath = "./wallet.json"
+	// DefaultProfile is used when neither the flags, the environment nor the file pick one
+	DefaultProfile = "sepolia"
+)
+
+// File is a config file. Profile picks the profile used when none is given, and Profiles
+// overrides fields of the built-in networks or adds custom ones on top of Defaults.
+type File struct {
+	Profile  string                     `json:"profile"`
+	Profiles map[string]json.RawMessage `json:"profiles"`
+}
+
+// profileFields are the fields a profile may set. MaxPriceAge is written as a duration such as "90m".
+type profileFields struct {
+	*Config
+	MaxPriceAge string `json:"maxPriceAge"`
+}
+
+// ReadFile reads a config file
+func ReadFile(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var file File
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return &file, nil
+}
+
+// Resolve returns the named profile: a built-in network or Defaults, with the fields the file
+// sets for that name on top
+func (f *File) Resolve(name string) (Config, error) {
+	cfg, builtIn := Networks[name]
+	raw, inFile := f.Profiles[name]
+	if !builtIn && !inFile {
+		return Config{}, fmt.Errorf("unknown profile %q; choose one of %s", name, strings.Join(f.names(), ", "))
+	}
+	if !builtIn {
+		cfg = Defaults
+	}
+	cfg.Name = name
+	if !inFile {
+		return cfg, nil
+	}
+
+	fields := profileFields{Config: &cfg}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&fields); err != nil {
+		return Config{}, fmt.Errorf("invalid profile %q: %w", name, err)
+	}
+	if fields.MaxPriceAge != "" {
+		age, err := time.ParseDuration(fields.MaxPriceAge)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid profile %q: maxPriceAge: %w", name, err)
+		}
+		cfg.MaxPriceAge = age
+	}
+	return cfg, nil
+}
+
+// names lists the built-in and file profiles
+func (f *File) names() []string {
+	seen := make(map[string]bool)
+	for name := range Networks {
+		seen[name] = true
+	}
+	for name := range f.Profiles {
+		seen[name] = true
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// setting is a field that can be set by a flag and an environment variable
+type setting struct {
+	flag  string
+	env   string
+	usage string
+	value *string
+}
+
+// Load builds the configuration from, in increasing precedence, the built-in networks, the
+// profile in the config file, environment variables and the command-line flags in args.
+// getenv is usually os.Getenv. It returns flag.ErrHelp when args ask for usage.
+func Load(args []string, getenv func(string) string) (Config, error) {
+	var path, profile, rpcURL, explorerURL, chainID, tokenRegistry, gasStrategy, priceOracle string
+	settings := []setting{
+		{"config", "WALLET_CONFIG", "config file with network profiles (default " + DefaultPath + ")", &path},
+		{"profile", "WALLET_PROFILE", "network profile: mainnet, sepolia, holesky or one from the config file", &profile},
+		{"rpc-url", "WALLET_RPC_URL", "RPC node URL", &rpcURL},
+		{"explorer-url", "WALLET_EXPLORER_URL", "block explorer URL", &explorerURL},
+		{"chain-id", "WALLET_CHAIN_ID", "chain ID the RPC node must be on, 0 to skip the check", &chainID},
+		{"token-registry", "WALLET_TOKEN_REGISTRY", "token registry file", &tokenRegistry},
+		{"gas-strategy", "WALLET_GAS_STRATEGY", "gas strategy: suggested, multiplier, fee-history or fixed-cap", &gasStrategy},
+		{"price-oracle", "WALLET_PRICE_ORACLE", "ETH price oracle: median, coinbase or chainlink", &priceOracle},
+	}
+
+	flags := flag.NewFlagSet("transfer", flag.ContinueOnError)
+	for _, s := range settings {
+		flags.StringVar(s.value, s.flag, "", s.usage+" ($"+s.env+")")
+	}
+	if err := flags.Parse(args); err != nil {
+		return Config{}, err
+	}
+	if flags.NArg() > 0 {
+		return Config{}, fmt.Errorf("unexpected argument %q", flags.Arg(0))
+	}
+	for _, s := range settings {
+		if *s.value == "" {
+			*s.value = getenv(s.env)
+		}
+	}
+
+	file := &File{}
+	if path != "" {
+		var err error
+		if file, err = ReadFile(path); err != nil {
+			return Config{}, err
+		}
+	} else if _, err := os.Stat(DefaultPath); err == nil {
+		if file, err = ReadFile(DefaultPath); err != nil {
+			return Config{}, err
+		}
+	}
+
+	if profile == "" {
+		profile = file.Profile
+	}
+	if profile == "" {
+		profile = DefaultProfile
+	}
+	cfg, err := file.Resolve(profile)
+	if err != nil {
+		return Config{}, err
+	}
+
+	if rpcURL != "" {
+		cfg.PublicNodeUrl = rpcURL
+	}
+	if explorerURL != "" {
+		cfg.EthereumExplorerUrl = explorerURL
+	}
+	if chainID != "" {
+		if cfg.ChainID, err = strconv.ParseUint(chainID, 10, 64); err != nil {
+			return Config{}, fmt.Errorf("invalid chain ID %q", chainID)
+		}
+	}
+	if tokenRegistry != "" {
+		cfg.TokenRegistryPath = tokenRegistry
+	}
+	if gasStrategy != "" {
+		cfg.GasStrategy = gasStrategy
+	}
+	if priceOracle != "" {
+		cfg.PriceOracle = priceOracle
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, fmt.Errorf("profile %q: %w", cfg.Name, err)
+	}
+	return cfg, nil
+}
+
+// Validate checks that the configuration names a node, a known gas strategy and a usable price oracle
+func (cfg Config) Validate() error {
+	if cfg.PublicNodeUrl == "" {
+		return errors.New("rpcUrl is required")
+	}
+	if cfg.TokenRegistryPath == "" {
+		return errors.New("tokenRegistry is required")
+	}
+
+	switch cfg.GasStrategy {
+	case "", GasStrategySuggested, GasStrategyMultiplier, GasStrategyFeeHistory, GasStrategyFixedCap:
+	default:
+		return fmt.Errorf("unknown gas strategy %q", cfg.GasStrategy)
+	}
+
+	switch cfg.PriceOracle {
+	case PriceOracleCoinbase:
+	case PriceOracleMedian, PriceOracleChainlink:
+		if cfg.ChainlinkETHUSDFeed == "" {
+			return fmt.Errorf("price oracle %q needs chainlinkEthUsdFeed", cfg.PriceOracle)
+		}
+	default:
+		return fmt.Errorf("unknown price oracle %q", cfg.PriceOracle)
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+// transfer/config/load_test.go
+
+package config
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testFile = `{
+  "profile": "devnet",
+  "profiles": {
+    "mainnet": {"rpcUrl": "https://mainnet.example", "gasStrategy": "fee-history"},
+    "devnet": {
+      "rpcUrl": "http://localhost:8545",
+      "chainId": 1337,
+      "tokenRegistry": "./tokens/devnet.json",
+      "maxPriceAge": "90m",
+      "confirmations": 0
+    }
+  }
+}`
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "wallet.json")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func env(values map[string]string) func(string) string {
+	return func(key string) string { return values[key] }
+}
+
+func TestLoadDefaultProfile(t *testing.T) {
+	cfg, err := Load(nil, env(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Name != "sepolia" || cfg.PublicNodeUrl != SepoliaTestnet.PublicNodeUrl || cfg.ChainID != 11155111 {
+		t.Errorf("cfg = %+v, want the Sepolia profile", cfg)
+	}
+}
+
+func TestLoadFileProfiles(t *testing.T) {
+	path := writeConfig(t, testFile)
+
+	cfg, err := Load([]string{"-config", path}, env(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Name != "devnet" || cfg.ChainID != 1337 || cfg.PublicNodeUrl != "http://localhost:8545" {
+		t.Errorf("custom profile = %+v", cfg)
+	}
+	if cfg.MaxPriceAge != 90*time.Minute || cfg.Confirmations != 0 {
+		t.Errorf("MaxPriceAge = %s, Confirmations = %d", cfg.MaxPriceAge, cfg.Confirmations)
+	}
+	if cfg.Currency != Defaults.Currency || cfg.PriceOracle != PriceOracleCoinbase {
+		t.Errorf("custom profile should start from Defaults, got %+v", cfg)
+	}
+
+	cfg, err = Load([]string{"-config", path, "-profile", "mainnet"}, env(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.PublicNodeUrl != "https://mainnet.example" || cfg.GasStrategy != GasStrategyFeeHistory {
+		t.Errorf("file fields not applied to mainnet: %+v", cfg)
+	}
+	if cfg.ChainID != 1 || cfg.ChainlinkETHUSDFeed != EthereumMainnet.ChainlinkETHUSDFeed {
+		t.Errorf("built-in mainnet fields lost: %+v", cfg)
+	}
+
+	cfg, err = Load([]string{"-config", path, "-profile", "holesky"}, env(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ChainID != 17000 || cfg.PriceOracle != PriceOracleCoinbase {
+		t.Errorf("holesky = %+v", cfg)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeConfig(t, testFile)
+	environment := env(map[string]string{
+		"WALLET_CONFIG":       path,
+		"WALLET_PROFILE":      "mainnet",
+		"WALLET_RPC_URL":      "https://env.example",
+		"WALLET_CHAIN_ID":     "5",
+		"WALLET_GAS_STRATEGY": "suggested",
+	})
+
+	cfg, err := Load([]string{"-rpc-url", "https://flag.example", "-price-oracle", "chainlink"}, environment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Name != "mainnet" {
+		t.Errorf("profile = %s, want mainnet from the environment", cfg.Name)
+	}
+	if cfg.PublicNodeUrl != "https://flag.example" {
+		t.Errorf("rpcUrl = %s, want the flag to win over the environment", cfg.PublicNodeUrl)
+	}
+	if cfg.ChainID != 5 || cfg.GasStrategy != GasStrategySuggested {
+		t.Errorf("environment overrides not applied: %+v", cfg)
+	}
+	if cfg.PriceOracle != PriceOracleChainlink {
+		t.Errorf("priceOracle = %s, want chainlink", cfg.PriceOracle)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	path := writeConfig(t, testFile)
+	tests := []struct {
+		name string
+		args []string
+		file string
+		want string
+	}{
+		{"unknown profile", []string{"-profile", "goerli"}, "", "unknown profile"},
+		{"bad gas strategy", []string{"-gas-strategy", "fastest"}, "", "unknown gas strategy"},
+		{"bad chain ID", []string{"-chain-id", "one"}, "", "invalid chain ID"},
+		{"oracle without feed", []string{"-profile", "holesky", "-price-oracle", "median"}, "", "needs chainlinkEthUsdFeed"},
+		{"missing file", []string{"-config", filepath.Join(t.TempDir(), "missing.json")}, "", "failed to read config file"},
+		{"unknown field", nil, `{"profile": "devnet", "profiles": {"devnet": {"rpcUrl": "http://localhost:8545", "rpc": "x"}}}`, "unknown field"},
+		{"custom without RPC", []string{"-profile", "devnet"}, `{"profiles": {"devnet": {"chainId": 1337}}}`, "rpcUrl is required"},
+		{"stray argument", []string{"mainnet"}, "", "unexpected argument"},
+	}
+	for _, tt := range tests {
+		args := tt.args
+		if tt.file != "" {
+			args = append([]string{"-config", writeConfig(t, tt.file)}, args...)
+		} else if len(args) > 0 && args[0] != "-config" {
+			args = append([]string{"-config", path}, args...)
+		}
+		_, err := Load(args, env(nil))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestLoadHelp(t *testing.T) {
+	if _, err := Load([]string{"-h"}, env(nil)); !errors.Is(err, flag.ErrHelp) {
+		t.Errorf("err = %v, want flag.ErrHelp", err)
+	}
+}
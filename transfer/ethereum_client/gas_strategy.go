@@ -0,0 +1,207 @@
+// ethereum_client/gas_strategy.go
+
+package ethereum_client
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const baseFeeFactor = 2
+
+// GasFees holds the per-gas prices chosen by a GasStrategy.
+// GasTipCap and GasFeeCap are set on London-enabled chains, GasPrice otherwise.
+type GasFees struct {
+	GasPrice  *big.Int
+	GasTipCap *big.Int
+	GasFeeCap *big.Int
+}
+
+// IsDynamic reports whether the fees describe an EIP-1559 transaction
+func (f *GasFees) IsDynamic() bool {
+	return f.GasTipCap != nil && f.GasFeeCap != nil
+}
+
+// MaxGasPrice returns the most the sender can pay per unit of gas
+func (f *GasFees) MaxGasPrice() *big.Int {
+	if f.IsDynamic() {
+		return f.GasFeeCap
+	}
+	return f.GasPrice
+}
+
+// FeeBackend is the part of the node a GasStrategy reads from. *ethclient.Client implements it.
+type FeeBackend interface {
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	SuggestGasPrice(ctx context.Context) (*big.Int, error)
+	SuggestGasTipCap(ctx context.Context) (*big.Int, error)
+	FeeHistory(ctx context.Context, blockCount uint64, lastBlock *big.Int, rewardPercentiles []float64) (*ethereum.FeeHistory, error)
+}
+
+// GasStrategy decides how much to pay for gas
+type GasStrategy interface {
+	Name() string
+	Fees(ctx context.Context, backend FeeBackend) (*GasFees, error)
+}
+
+// SuggestedStrategy pays what the node suggests
+type SuggestedStrategy struct{}
+
+func (s *SuggestedStrategy) Name() string {
+	return "Suggested"
+}
+
+func (s *SuggestedStrategy) Fees(ctx context.Context, backend FeeBackend) (*GasFees, error) {
+	baseFee, err := latestBaseFee(ctx, backend)
+	if err != nil {
+		return nil, err
+	}
+
+	if baseFee == nil {
+		gasPrice, err := backend.SuggestGasPrice(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to suggest gas price: %w", err)
+		}
+		return &GasFees{GasPrice: gasPrice}, nil
+	}
+
+	gasTipCap, err := backend.SuggestGasTipCap(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to suggest gas tip cap: %w", err)
+	}
+	return &GasFees{GasTipCap: gasTipCap, GasFeeCap: feeCap(baseFee, gasTipCap)}, nil
+}
+
+// MultiplierStrategy scales the suggested fees by Factor
+type MultiplierStrategy struct {
+	Factor float64
+}
+
+func (s *MultiplierStrategy) Name() string {
+	return fmt.Sprintf("Multiplier (x%.2f)", s.Factor)
+}
+
+func (s *MultiplierStrategy) Fees(ctx context.Context, backend FeeBackend) (*GasFees, error) {
+	if s.Factor <= 0 {
+		return nil, fmt.Errorf("gas price factor must be greater than zero")
+	}
+
+	fees, err := (&SuggestedStrategy{}).Fees(ctx, backend)
+	if err != nil {
+		return nil, err
+	}
+
+	if !fees.IsDynamic() {
+		return &GasFees{GasPrice: scale(fees.GasPrice, s.Factor)}, nil
+	}
+	return &GasFees{GasTipCap: scale(fees.GasTipCap, s.Factor), GasFeeCap: scale(fees.GasFeeCap, s.Factor)}, nil
+}
+
+// FeeHistoryStrategy pays the given percentile of priority fees paid over the last Blocks blocks
+type FeeHistoryStrategy struct {
+	Blocks     uint64
+	Percentile float64
+}
+
+func (s *FeeHistoryStrategy) Name() string {
+	return fmt.Sprintf("Fee history (p%.0f over %d blocks)", s.Percentile, s.Blocks)
+}
+
+func (s *FeeHistoryStrategy) Fees(ctx context.Context, backend FeeBackend) (*GasFees, error) {
+	if s.Blocks == 0 {
+		return nil, fmt.Errorf("fee history block count must be greater than zero")
+	}
+	if s.Percentile < 0 || s.Percentile > 100 {
+		return nil, fmt.Errorf("fee history percentile must be between 0 and 100")
+	}
+
+	history, err := backend.FeeHistory(ctx, s.Blocks, nil, []float64{s.Percentile})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fee history: %w", err)
+	}
+
+	var rewards []*big.Int
+	for _, blockRewards := range history.Reward {
+		if len(blockRewards) > 0 && blockRewards[0] != nil {
+			rewards = append(rewards, blockRewards[0])
+		}
+	}
+	if len(rewards) == 0 {
+		return nil, fmt.Errorf("fee history returned no rewards")
+	}
+	sort.Slice(rewards, func(i, j int) bool { return rewards[i].Cmp(rewards[j]) < 0 })
+	gasTipCap := rewards[len(rewards)/2]
+
+	// The last base fee in the history is the one for the next block
+	var baseFee *big.Int
+	if len(history.BaseFee) > 0 {
+		baseFee = history.BaseFee[len(history.BaseFee)-1]
+	}
+	if baseFee == nil || baseFee.Sign() == 0 {
+		return &GasFees{GasPrice: gasTipCap}, nil
+	}
+	return &GasFees{GasTipCap: gasTipCap, GasFeeCap: feeCap(baseFee, gasTipCap)}, nil
+}
+
+// FixedCapStrategy pays a fixed maximum of MaxGwei per unit of gas
+type FixedCapStrategy struct {
+	MaxGwei float64
+}
+
+func (s *FixedCapStrategy) Name() string {
+	return fmt.Sprintf("Fixed cap (%.2f Gwei)", s.MaxGwei)
+}
+
+func (s *FixedCapStrategy) Fees(ctx context.Context, backend FeeBackend) (*GasFees, error) {
+	if s.MaxGwei <= 0 {
+		return nil, fmt.Errorf("gas price cap must be greater than zero")
+	}
+	maxGasPrice := scale(big.NewInt(1e9), s.MaxGwei)
+
+	baseFee, err := latestBaseFee(ctx, backend)
+	if err != nil {
+		return nil, err
+	}
+
+	if baseFee == nil {
+		return &GasFees{GasPrice: maxGasPrice}, nil
+	}
+	if baseFee.Cmp(maxGasPrice) >= 0 {
+		return nil, fmt.Errorf("gas price cap %.2f Gwei is below the current base fee", s.MaxGwei)
+	}
+
+	gasTipCap, err := backend.SuggestGasTipCap(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to suggest gas tip cap: %w", err)
+	}
+	if headroom := new(big.Int).Sub(maxGasPrice, baseFee); gasTipCap.Cmp(headroom) > 0 {
+		gasTipCap = headroom
+	}
+	return &GasFees{GasTipCap: gasTipCap, GasFeeCap: maxGasPrice}, nil
+}
+
+// latestBaseFee returns the base fee of the latest block, or nil before London
+func latestBaseFee(ctx context.Context, backend FeeBackend) (*big.Int, error) {
+	header, err := backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest header: %w", err)
+	}
+	return header.BaseFee, nil
+}
+
+// feeCap leaves room for the base fee to double before the transaction is included
+func feeCap(baseFee, gasTipCap *big.Int) *big.Int {
+	gasFeeCap := new(big.Int).Mul(baseFee, big.NewInt(baseFeeFactor))
+	return gasFeeCap.Add(gasFeeCap, gasTipCap)
+}
+
+func scale(value *big.Int, factor float64) *big.Int {
+	scaled := new(big.Float).Mul(new(big.Float).SetInt(value), big.NewFloat(factor))
+	result, _ := scaled.Int(nil)
+	return result
+}
@@ -0,0 +1,125 @@
+// ethereum_client/gas_strategy_test.go
+
+package ethereum_client
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func gwei(n int64) *big.Int {
+	return new(big.Int).Mul(big.NewInt(n), big.NewInt(1_000_000_000))
+}
+
+// fakeFeeBackend answers with fixed fees; a nil baseFee is a chain before London
+type fakeFeeBackend struct {
+	baseFee   *big.Int
+	gasPrice  *big.Int
+	gasTipCap *big.Int
+	history   *ethereum.FeeHistory
+
+	percentiles []float64
+}
+
+func (f *fakeFeeBackend) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return &types.Header{Number: big.NewInt(100), BaseFee: f.baseFee}, nil
+}
+
+func (f *fakeFeeBackend) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	return f.gasPrice, nil
+}
+
+func (f *fakeFeeBackend) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	return f.gasTipCap, nil
+}
+
+func (f *fakeFeeBackend) FeeHistory(ctx context.Context, blockCount uint64, lastBlock *big.Int, rewardPercentiles []float64) (*ethereum.FeeHistory, error) {
+	f.percentiles = rewardPercentiles
+	return f.history, nil
+}
+
+func TestGasStrategies(t *testing.T) {
+	london := func() *fakeFeeBackend {
+		return &fakeFeeBackend{baseFee: gwei(10), gasPrice: gwei(12), gasTipCap: gwei(2)}
+	}
+	legacy := func() *fakeFeeBackend {
+		return &fakeFeeBackend{gasPrice: gwei(12), gasTipCap: gwei(2)}
+	}
+	withHistory := func(baseFee *big.Int, rewards ...*big.Int) *fakeFeeBackend {
+		history := &ethereum.FeeHistory{BaseFee: []*big.Int{gwei(8), baseFee}}
+		for _, reward := range rewards {
+			history.Reward = append(history.Reward, []*big.Int{reward})
+		}
+		return &fakeFeeBackend{history: history}
+	}
+
+	tests := []struct {
+		name     string
+		strategy GasStrategy
+		backend  *fakeFeeBackend
+		want     *GasFees
+		wantErr  string
+	}{
+		{"suggested", &SuggestedStrategy{}, london(), &GasFees{GasTipCap: gwei(2), GasFeeCap: gwei(22)}, ""},
+		{"suggested before London", &SuggestedStrategy{}, legacy(), &GasFees{GasPrice: gwei(12)}, ""},
+
+		{"multiplier", &MultiplierStrategy{Factor: 1.5}, london(), &GasFees{GasTipCap: gwei(3), GasFeeCap: gwei(33)}, ""},
+		{"multiplier before London", &MultiplierStrategy{Factor: 1.5}, legacy(), &GasFees{GasPrice: gwei(18)}, ""},
+		{"multiplier without factor", &MultiplierStrategy{}, london(), nil, "factor"},
+
+		{"fee history median", &FeeHistoryStrategy{Blocks: 3, Percentile: 60}, withHistory(gwei(10), gwei(5), gwei(1), gwei(3)),
+			&GasFees{GasTipCap: gwei(3), GasFeeCap: gwei(23)}, ""},
+		{"fee history skips empty blocks", &FeeHistoryStrategy{Blocks: 3, Percentile: 60}, withHistory(gwei(10), gwei(4), nil, gwei(2)),
+			&GasFees{GasTipCap: gwei(4), GasFeeCap: gwei(24)}, ""},
+		{"fee history before London", &FeeHistoryStrategy{Blocks: 2, Percentile: 50}, withHistory(big.NewInt(0), gwei(1), gwei(3)),
+			&GasFees{GasPrice: gwei(3)}, ""},
+		{"fee history without rewards", &FeeHistoryStrategy{Blocks: 2, Percentile: 50}, withHistory(gwei(10)), nil, "no rewards"},
+		{"fee history without blocks", &FeeHistoryStrategy{Percentile: 50}, withHistory(gwei(10), gwei(1)), nil, "block count"},
+		{"fee history percentile out of range", &FeeHistoryStrategy{Blocks: 2, Percentile: 101}, withHistory(gwei(10), gwei(1)), nil, "percentile"},
+
+		{"fixed cap", &FixedCapStrategy{MaxGwei: 30}, london(), &GasFees{GasTipCap: gwei(2), GasFeeCap: gwei(30)}, ""},
+		{"fixed cap limits the tip to the headroom", &FixedCapStrategy{MaxGwei: 11}, london(), &GasFees{GasTipCap: gwei(1), GasFeeCap: gwei(11)}, ""},
+		{"fixed cap at the base fee", &FixedCapStrategy{MaxGwei: 10}, london(), nil, "below the current base fee"},
+		{"fixed cap before London", &FixedCapStrategy{MaxGwei: 30}, legacy(), &GasFees{GasPrice: gwei(30)}, ""},
+		{"fixed cap without cap", &FixedCapStrategy{}, london(), nil, "greater than zero"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fees, err := tt.strategy.Fees(context.Background(), tt.backend)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !sameFee(fees.GasPrice, tt.want.GasPrice) || !sameFee(fees.GasTipCap, tt.want.GasTipCap) || !sameFee(fees.GasFeeCap, tt.want.GasFeeCap) {
+				t.Errorf("fees = %v/%v/%v, want %v/%v/%v", fees.GasPrice, fees.GasTipCap, fees.GasFeeCap, tt.want.GasPrice, tt.want.GasTipCap, tt.want.GasFeeCap)
+			}
+		})
+	}
+}
+
+func TestFeeHistoryRequestsPercentile(t *testing.T) {
+	backend := &fakeFeeBackend{history: &ethereum.FeeHistory{Reward: [][]*big.Int{{gwei(1)}}, BaseFee: []*big.Int{gwei(10)}}}
+	if _, err := (&FeeHistoryStrategy{Blocks: 1, Percentile: 75}).Fees(context.Background(), backend); err != nil {
+		t.Fatal(err)
+	}
+	if len(backend.percentiles) != 1 || backend.percentiles[0] != 75 {
+		t.Errorf("percentiles = %v, want [75]", backend.percentiles)
+	}
+}
+
+func sameFee(got, want *big.Int) bool {
+	if got == nil || want == nil {
+		return got == nil && want == nil
+	}
+	return got.Cmp(want) == 0
+}
@@ -0,0 +1,434 @@
+// transfer/nonce/nonce.go
+
+package nonce
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"go-ethereum-wallet/internal/atomicfile"
+	"go-ethereum-wallet/internal/filelock"
+)
+
+// Backend is the part of the RPC client the manager needs; *ethclient.Client implements it
+type Backend interface {
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+	TransactionByHash(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error)
+}
+
+// Entry is a nonce handed out by the manager that is not yet mined.
+// TxHash and RawTx are empty until the transaction using the nonce has been sent, and
+// Released marks a nonce given back without ever being sent. MissingSince and MissingChecks
+// track how long the node has not known the transaction.
+type Entry struct {
+	Nonce         uint64     `json:"nonce"`
+	TxHash        string     `json:"txHash,omitempty"`
+	RawTx         string     `json:"rawTx,omitempty"`
+	Released      bool       `json:"released,omitempty"`
+	AssignedAt    time.Time  `json:"assignedAt"`
+	MissingSince  *time.Time `json:"missingSince,omitempty"`
+	MissingChecks int        `json:"missingChecks,omitempty"`
+}
+
+// Transaction decodes the signed transaction sent with the nonce, or returns nil when it was not kept
+func (e Entry) Transaction() (*types.Transaction, error) {
+	if e.RawTx == "" {
+		return nil, nil
+	}
+	raw, err := hexutil.Decode(e.RawTx)
+	if err != nil {
+		return nil, fmt.Errorf("invalid raw transaction for nonce %d: %w", e.Nonce, err)
+	}
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(raw); err != nil {
+		return nil, fmt.Errorf("invalid raw transaction for nonce %d: %w", e.Nonce, err)
+	}
+	return tx, nil
+}
+
+// state is the file kept per account and chain
+type state struct {
+	Address string  `json:"address"`
+	ChainID uint64  `json:"chainId"`
+	Next    uint64  `json:"next"`
+	Pending []Entry `json:"pending"`
+}
+
+// Reconciliation compares the local state with the chain. Mined is the number of transactions
+// the account has in blocks and Next the nonce the manager would hand out next.
+// Gaps are the assigned nonces at or above the node's pending nonce that were released unsent,
+// or whose transaction the node has not known for DropChecks checks over at least DropAfter.
+// Transactions after a gap cannot be mined until the gap is filled. Missing are nonces whose
+// transaction the node does not know yet, as happens right after sending through a lagging node.
+type Reconciliation struct {
+	Mined   uint64
+	Next    uint64
+	Pending []Entry
+	Gaps    []uint64
+	Missing []uint64
+}
+
+// Defaults for how long a transaction must be unknown to the node before it counts as dropped
+const (
+	DefaultDropAfter  = 10 * time.Minute
+	DefaultDropChecks = 3
+)
+
+// Manager hands out nonces per account and chain, remembering them in dir so that
+// transfers sent in quick succession, even from separate runs, never reuse a nonce
+// that a lagging node still reports as free. Each call holds an exclusive lock on the
+// account's state file, so transfers running at the same time get distinct nonces. A transaction counts as dropped once the node has
+// not known it for DropChecks reconciliations spanning at least DropAfter.
+type Manager struct {
+	DropAfter  time.Duration
+	DropChecks int
+
+	dir     string
+	backend Backend
+	chainID *big.Int
+	now     func() time.Time
+	mu      sync.Mutex
+}
+
+func NewManager(dir string, backend Backend, chainID *big.Int) *Manager {
+	return &Manager{
+		DropAfter:  DefaultDropAfter,
+		DropChecks: DefaultDropChecks,
+		dir:        dir,
+		backend:    backend,
+		chainID:    chainID,
+		now:        time.Now,
+	}
+}
+
+// Reconcile drops mined nonces from the local state, catches up with nonces used elsewhere
+// and reports gaps
+func (m *Manager) Reconcile(ctx context.Context, account common.Address) (*Reconciliation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	unlock, err := m.lock(account)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	s, err := m.load(account)
+	if err != nil {
+		return nil, err
+	}
+	result, err := m.reconcile(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.save(account, s); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Next reconciles and assigns a fresh nonce above every nonce known locally or to the node
+func (m *Manager) Next(ctx context.Context, account common.Address) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	unlock, err := m.lock(account)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	s, err := m.load(account)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := m.reconcile(ctx, s); err != nil {
+		return 0, err
+	}
+
+	nonce := s.Next
+	s.Next++
+	s.assign(nonce)
+
+	if err := m.save(account, s); err != nil {
+		return 0, err
+	}
+	return nonce, nil
+}
+
+// Reserve assigns the next nonce and passes it to build, which prepares the transaction that
+// will use it. If build fails, the nonce is released before Reserve returns its error, so a
+// transfer that stops before sending does not leave a nonce gap behind for the next one.
+func (m *Manager) Reserve(ctx context.Context, account common.Address, build func(nonce uint64) error) (uint64, error) {
+	nonce, err := m.Next(ctx, account)
+	if err != nil {
+		return 0, err
+	}
+	if err := build(nonce); err != nil {
+		if releaseErr := m.Release(account, nonce); releaseErr != nil {
+			return nonce, fmt.Errorf("%w (and failed to release nonce %d: %v)", err, nonce, releaseErr)
+		}
+		return nonce, err
+	}
+	return nonce, nil
+}
+
+// Assign marks a specific nonce as in use, e.g. to fill a gap reported by Reconcile
+func (m *Manager) Assign(account common.Address, nonce uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	unlock, err := m.lock(account)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	s, err := m.load(account)
+	if err != nil {
+		return err
+	}
+	s.assign(nonce).Released = false
+	if nonce >= s.Next {
+		s.Next = nonce + 1
+	}
+	return m.save(account, s)
+}
+
+// Record stores the signed transaction sent with its nonce, replacing any earlier one such
+// as a sped-up or cancelled transaction, so it can be rebroadcast if the node drops it
+func (m *Manager) Record(account common.Address, tx *types.Transaction) error {
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("failed to encode transaction: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	unlock, err := m.lock(account)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	s, err := m.load(account)
+	if err != nil {
+		return err
+	}
+	entry := s.assign(tx.Nonce())
+	entry.TxHash = tx.Hash().Hex()
+	entry.RawTx = hexutil.Encode(raw)
+	entry.Released = false
+	entry.found()
+	if tx.Nonce() >= s.Next {
+		s.Next = tx.Nonce() + 1
+	}
+	return m.save(account, s)
+}
+
+// Release gives back a nonce whose transaction could not be sent. The newest nonce is
+// reused by the next call to Next; an older one is left in place and shows up as a gap
+// right away, since nothing was broadcast with it.
+func (m *Manager) Release(account common.Address, nonce uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	unlock, err := m.lock(account)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	s, err := m.load(account)
+	if err != nil {
+		return err
+	}
+	if nonce+1 == s.Next {
+		s.remove(nonce)
+		s.Next = nonce
+	} else if entry := s.find(nonce); entry != nil && entry.TxHash == "" {
+		entry.Released = true
+	}
+	return m.save(account, s)
+}
+
+func (m *Manager) reconcile(ctx context.Context, s *state) (*Reconciliation, error) {
+	mined, err := m.backend.NonceAt(ctx, common.HexToAddress(s.Address), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get nonce: %w", err)
+	}
+	pending, err := m.backend.PendingNonceAt(ctx, common.HexToAddress(s.Address))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending nonce: %w", err)
+	}
+
+	kept := s.Pending[:0]
+	for _, entry := range s.Pending {
+		if entry.Nonce >= mined {
+			kept = append(kept, entry)
+		}
+	}
+	s.Pending = kept
+
+	if pending > s.Next {
+		s.Next = pending
+	}
+	if mined > s.Next {
+		s.Next = mined
+	}
+
+	var gaps, missing []uint64
+	now := m.now().UTC()
+	for i := range s.Pending {
+		entry := &s.Pending[i]
+		switch {
+		case entry.Nonce < pending:
+			// The node holds a transaction with this nonce, even if it cannot find ours by hash yet
+			entry.found()
+		case entry.Released:
+			gaps = append(gaps, entry.Nonce)
+		default:
+			known, err := m.known(ctx, entry)
+			if err != nil {
+				return nil, err
+			}
+			if known {
+				entry.found()
+				continue
+			}
+			if entry.MissingSince == nil {
+				entry.MissingSince = &now
+			}
+			entry.MissingChecks++
+			if entry.MissingChecks >= m.DropChecks && now.Sub(*entry.MissingSince) >= m.DropAfter {
+				gaps = append(gaps, entry.Nonce)
+			} else {
+				missing = append(missing, entry.Nonce)
+			}
+		}
+	}
+
+	return &Reconciliation{
+		Mined:   mined,
+		Next:    s.Next,
+		Pending: append([]Entry(nil), s.Pending...),
+		Gaps:    gaps,
+		Missing: missing,
+	}, nil
+}
+
+// known reports whether the node knows the transaction sent with the entry's nonce
+func (m *Manager) known(ctx context.Context, entry *Entry) (bool, error) {
+	if entry.TxHash == "" {
+		return false, nil
+	}
+	_, _, err := m.backend.TransactionByHash(ctx, common.HexToHash(entry.TxHash))
+	if errors.Is(err, ethereum.NotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to look up transaction %s: %w", entry.TxHash, err)
+	}
+	return true, nil
+}
+
+// found resets the missing count once the node knows the transaction again
+func (e *Entry) found() {
+	e.MissingSince = nil
+	e.MissingChecks = 0
+}
+
+// assign returns the entry for nonce, adding it when it is new
+func (s *state) assign(nonce uint64) *Entry {
+	if entry := s.find(nonce); entry != nil {
+		return entry
+	}
+	s.Pending = append(s.Pending, Entry{Nonce: nonce, AssignedAt: time.Now().UTC()})
+	sort.Slice(s.Pending, func(i, j int) bool { return s.Pending[i].Nonce < s.Pending[j].Nonce })
+	return s.find(nonce)
+}
+
+func (s *state) find(nonce uint64) *Entry {
+	for i := range s.Pending {
+		if s.Pending[i].Nonce == nonce {
+			return &s.Pending[i]
+		}
+	}
+	return nil
+}
+
+func (s *state) remove(nonce uint64) {
+	for i, entry := range s.Pending {
+		if entry.Nonce == nonce {
+			s.Pending = append(s.Pending[:i], s.Pending[i+1:]...)
+			return
+		}
+	}
+}
+
+// lock takes the exclusive lock on the account's state file, so that transfers running in
+// separate processes load, reconcile and save the state one after another
+func (m *Manager) lock(account common.Address) (func(), error) {
+	path := m.stateFile(account)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create nonce directory: %w", err)
+	}
+	lock, err := filelock.Acquire(path + ".lock")
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock nonce state: %w", err)
+	}
+	return func() { lock.Release() }, nil
+}
+
+func (m *Manager) stateFile(account common.Address) string {
+	return filepath.Join(m.dir, m.chainID.String(), account.Hex()+".json")
+}
+
+func (m *Manager) load(account common.Address) (*state, error) {
+	data, err := os.ReadFile(m.stateFile(account))
+	if errors.Is(err, os.ErrNotExist) {
+		return &state{Address: account.Hex(), ChainID: m.chainID.Uint64()}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read nonce state: %w", err)
+	}
+
+	var s state
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse nonce state %s: %w", m.stateFile(account), err)
+	}
+	return &s, nil
+}
+
+// save replaces the state file atomically, so an interrupted run never leaves a truncated file behind
+func (m *Manager) save(account common.Address, s *state) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode nonce state: %w", err)
+	}
+
+	path := m.stateFile(account)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create nonce directory: %w", err)
+	}
+
+	if err := atomicfile.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write nonce state: %w", err)
+	}
+	return nil
+}
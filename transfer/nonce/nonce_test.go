@@ -0,0 +1,303 @@
+// transfer/nonce/nonce_test.go
+
+package nonce
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+var testAccount = common.HexToAddress("0x9858EfFD232B4033E47d90003D41EC34EcaEda94")
+
+type fakeBackend struct {
+	mined   uint64
+	pending uint64
+	known   map[common.Hash]bool
+}
+
+func (f *fakeBackend) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	return f.mined, nil
+}
+
+func (f *fakeBackend) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	return f.pending, nil
+}
+
+func (f *fakeBackend) TransactionByHash(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+	if f.known[hash] {
+		return nil, true, nil
+	}
+	return nil, false, ethereum.NotFound
+}
+
+func newTestManager(t *testing.T, backend *fakeBackend) *Manager {
+	return NewManager(t.TempDir(), backend, big.NewInt(11155111))
+}
+
+func send(t *testing.T, m *Manager, backend *fakeBackend) uint64 {
+	t.Helper()
+	n, err := m.Next(context.Background(), testAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := testTx(n, 1)
+	if err := m.Record(testAccount, tx); err != nil {
+		t.Fatal(err)
+	}
+	backend.known[tx.Hash()] = true
+	return n
+}
+
+// testTx is a distinct transaction for each nonce and value
+func testTx(nonce uint64, value int64) *types.Transaction {
+	return types.NewTx(&types.LegacyTx{Nonce: nonce, To: &testAccount, Value: big.NewInt(value), Gas: 21000, GasPrice: big.NewInt(1)})
+}
+
+// advance makes the manager's clock run forward by d on every reading
+func advance(m *Manager, d time.Duration) {
+	now := time.Now()
+	m.now = func() time.Time {
+		now = now.Add(d)
+		return now
+	}
+}
+
+func TestNextIgnoresStalePendingNonce(t *testing.T) {
+	backend := &fakeBackend{mined: 5, pending: 5, known: map[common.Hash]bool{}}
+	m := newTestManager(t, backend)
+
+	// The node keeps reporting 5 as pending, as a lagging public RPC does
+	for want := uint64(5); want < 8; want++ {
+		if got := send(t, m, backend); got != want {
+			t.Errorf("nonce = %d, want %d", got, want)
+		}
+	}
+}
+
+func TestNextCatchesUpWithChain(t *testing.T) {
+	backend := &fakeBackend{mined: 3, pending: 3, known: map[common.Hash]bool{}}
+	m := newTestManager(t, backend)
+	send(t, m, backend)
+
+	// Another wallet sent transactions from the same account
+	backend.mined, backend.pending = 10, 12
+	if got := send(t, m, backend); got != 12 {
+		t.Errorf("nonce = %d, want 12", got)
+	}
+}
+
+func TestStatePersists(t *testing.T) {
+	backend := &fakeBackend{mined: 0, pending: 0, known: map[common.Hash]bool{}}
+	dir := t.TempDir()
+
+	send(t, NewManager(dir, backend, big.NewInt(1)), backend)
+	if got := send(t, NewManager(dir, backend, big.NewInt(1)), backend); got != 1 {
+		t.Errorf("nonce after reload = %d, want 1", got)
+	}
+
+	// Another chain has its own state
+	if got := send(t, NewManager(dir, backend, big.NewInt(5)), backend); got != 0 {
+		t.Errorf("nonce on another chain = %d, want 0", got)
+	}
+}
+
+func TestReconcileDetectsGaps(t *testing.T) {
+	backend := &fakeBackend{mined: 0, pending: 0, known: map[common.Hash]bool{}}
+	m := newTestManager(t, backend)
+	for i := 0; i < 3; i++ {
+		send(t, m, backend)
+	}
+
+	// Nonce 0 is mined, nonce 1 is dropped by the node, nonce 2 is still pending
+	backend.mined, backend.pending = 1, 1
+	delete(backend.known, testTx(1, 1).Hash())
+	advance(m, 5*time.Minute)
+
+	// A single check, or a short absence, is not enough to call it dropped
+	for i := 1; i < DefaultDropChecks; i++ {
+		r, err := m.Reconcile(context.Background(), testAccount)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(r.Gaps) != 0 || !reflect.DeepEqual(r.Missing, []uint64{1}) {
+			t.Fatalf("check %d: gaps = %v, missing = %v; want no gaps and [1] missing", i, r.Gaps, r.Missing)
+		}
+	}
+
+	r, err := m.Reconcile(context.Background(), testAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(r.Gaps, []uint64{1}) {
+		t.Errorf("gaps = %v, want [1]", r.Gaps)
+	}
+	if len(r.Pending) != 2 || r.Next != 3 {
+		t.Errorf("pending = %d entries, next = %d; want 2 entries and next 3", len(r.Pending), r.Next)
+	}
+	if tx, err := r.Pending[0].Transaction(); err != nil || tx.Hash() != testTx(1, 1).Hash() {
+		t.Errorf("kept transaction = %v, %v; want the original for rebroadcast", tx, err)
+	}
+
+	// Filling the gap clears it
+	cancel := testTx(1, 0)
+	backend.known[cancel.Hash()] = true
+	if err := m.Record(testAccount, cancel); err != nil {
+		t.Fatal(err)
+	}
+	if r, _ := m.Reconcile(context.Background(), testAccount); len(r.Gaps) != 0 || len(r.Missing) != 0 {
+		t.Errorf("after filling: gaps = %v, missing = %v; want none", r.Gaps, r.Missing)
+	}
+}
+
+func TestReconcileTrustsPendingNonceOfLaggingNode(t *testing.T) {
+	backend := &fakeBackend{mined: 0, pending: 0, known: map[common.Hash]bool{}}
+	m := newTestManager(t, backend)
+	send(t, m, backend)
+	send(t, m, backend)
+
+	// The node counts both transactions in its pending nonce but cannot find them by hash
+	backend.pending = 2
+	backend.known = map[common.Hash]bool{}
+	advance(m, time.Hour)
+
+	for i := 0; i < 2*DefaultDropChecks; i++ {
+		r, err := m.Reconcile(context.Background(), testAccount)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(r.Gaps) != 0 || len(r.Missing) != 0 {
+			t.Fatalf("check %d: gaps = %v, missing = %v; want none while the pending nonce covers them", i, r.Gaps, r.Missing)
+		}
+	}
+}
+
+func TestReconcileWaitsForUnsentNonce(t *testing.T) {
+	backend := &fakeBackend{mined: 0, pending: 0, known: map[common.Hash]bool{}}
+	m := newTestManager(t, backend)
+
+	// A nonce taken but not yet recorded may belong to a transaction being broadcast right now
+	n, err := m.Next(context.Background(), testAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	send(t, m, backend)
+	r, err := m.Reconcile(context.Background(), testAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Gaps) != 0 || !reflect.DeepEqual(r.Missing, []uint64{n}) {
+		t.Errorf("gaps = %v, missing = %v; want no gaps and [%d] missing", r.Gaps, r.Missing, n)
+	}
+}
+
+func TestRelease(t *testing.T) {
+	backend := &fakeBackend{mined: 0, pending: 0, known: map[common.Hash]bool{}}
+	m := newTestManager(t, backend)
+
+	n, err := m.Next(context.Background(), testAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Release(testAccount, n); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := m.Next(context.Background(), testAccount); again != n {
+		t.Errorf("released nonce %d was not reused, got %d", n, again)
+	}
+
+	// Releasing an older nonce leaves a gap rather than reusing it out of order
+	send(t, m, backend)
+	if err := m.Release(testAccount, n); err != nil {
+		t.Fatal(err)
+	}
+	r, err := m.Reconcile(context.Background(), testAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(r.Gaps, []uint64{n}) {
+		t.Errorf("gaps = %v, want [%d]", r.Gaps, n)
+	}
+}
+
+func TestReserveReleasesOnFailedBuild(t *testing.T) {
+	backend := &fakeBackend{mined: 3, pending: 3, known: map[common.Hash]bool{}}
+	m := newTestManager(t, backend)
+
+	// A transfer that stops after taking its nonce, e.g. on a fee cap below the base fee
+	stopped := errors.New("fixed cap below the current base fee")
+	n, err := m.Reserve(context.Background(), testAccount, func(nonce uint64) error { return stopped })
+	if !errors.Is(err, stopped) {
+		t.Fatalf("err = %v, want the build error", err)
+	}
+
+	r, err := m.Reconcile(context.Background(), testAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Pending) != 0 || len(r.Gaps) != 0 || r.Next != n {
+		t.Errorf("pending = %v, gaps = %v, next = %d; want nonce %d free again", r.Pending, r.Gaps, r.Next, n)
+	}
+
+	// The next transfer gets the same nonce instead of waiting behind a gap
+	var built uint64
+	again, err := m.Reserve(context.Background(), testAccount, func(nonce uint64) error {
+		built = nonce
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != n || built != n {
+		t.Errorf("reserved %d and built with %d, want %d", again, built, n)
+	}
+	if r, _ := m.Reconcile(context.Background(), testAccount); len(r.Pending) != 1 || r.Pending[0].Nonce != n {
+		t.Errorf("pending = %v, want nonce %d kept after a successful build", r.Pending, n)
+	}
+}
+
+func TestNextAcrossManagersSharingDir(t *testing.T) {
+	backend := &fakeBackend{mined: 0, pending: 0, known: map[common.Hash]bool{}}
+	dir := t.TempDir()
+
+	// Separate managers stand in for transfers running in separate processes
+	const managers, perManager = 4, 5
+	nonces := make(chan uint64, managers*perManager)
+	var wg sync.WaitGroup
+	for i := 0; i < managers; i++ {
+		m := NewManager(dir, backend, big.NewInt(11155111))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perManager; j++ {
+				n, err := m.Next(context.Background(), testAccount)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				nonces <- n
+			}
+		}()
+	}
+	wg.Wait()
+	close(nonces)
+
+	seen := make(map[uint64]bool)
+	for n := range nonces {
+		if seen[n] {
+			t.Errorf("nonce %d handed out twice", n)
+		}
+		seen[n] = true
+	}
+	if len(seen) != managers*perManager {
+		t.Errorf("got %d distinct nonces, want %d", len(seen), managers*perManager)
+	}
+}
@@ -0,0 +1,375 @@
+// transfer/offline/offline.go
+
+package offline
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"go-ethereum-wallet/transfer/asset"
+	"go-ethereum-wallet/transfer/registry"
+	"go-ethereum-wallet/transfer/transaction"
+)
+
+// FormatVersion is the version of the unsigned and signed transaction files
+const FormatVersion = 1
+
+const (
+	TypeUnsigned = "unsigned"
+	TypeSigned   = "signed"
+)
+
+// transferSelector is the ERC-20 transfer(address,uint256) method ID
+var transferSelector = crypto.Keccak256([]byte("transfer(address,uint256)"))[:4]
+
+// Summary is what the transfer is meant to do, in human units. It is not trusted on its own:
+// the signer checks it against the transaction fields before anything is signed.
+type Summary struct {
+	Asset     string `json:"asset"`
+	Amount    string `json:"amount"`
+	Recipient string `json:"recipient"`
+}
+
+// UnsignedTransaction is built online and carried to the offline machine.
+// Wei values are decimal strings; GasPrice is set for legacy transactions,
+// MaxPriorityFeePerGas and MaxFeePerGas for EIP-1559 ones.
+type UnsignedTransaction struct {
+	Version              int     `json:"version"`
+	Type                 string  `json:"type"`
+	ChainID              uint64  `json:"chainId"`
+	From                 string  `json:"from"`
+	To                   string  `json:"to"`
+	Nonce                uint64  `json:"nonce"`
+	Gas                  uint64  `json:"gas"`
+	Value                string  `json:"value"`
+	Data                 string  `json:"data"`
+	GasPrice             string  `json:"gasPrice,omitempty"`
+	MaxPriorityFeePerGas string  `json:"maxPriorityFeePerGas,omitempty"`
+	MaxFeePerGas         string  `json:"maxFeePerGas,omitempty"`
+	Summary              Summary `json:"summary"`
+}
+
+// SignedTransaction carries the raw signed transaction back to the online machine
+type SignedTransaction struct {
+	Version        int     `json:"version"`
+	Type           string  `json:"type"`
+	ChainID        uint64  `json:"chainId"`
+	From           string  `json:"from"`
+	Nonce          uint64  `json:"nonce"`
+	Hash           string  `json:"hash"`
+	RawTransaction string  `json:"rawTransaction"`
+	Summary        Summary `json:"summary"`
+}
+
+// NewUnsignedTransaction describes tx on chainID for signing by from
+func NewUnsignedTransaction(tx *types.Transaction, chainID *big.Int, from common.Address, summary Summary) (*UnsignedTransaction, error) {
+	if tx.To() == nil {
+		return nil, errors.New("contract creations are not supported")
+	}
+	if chainID == nil || chainID.Sign() <= 0 {
+		return nil, errors.New("chain ID is required")
+	}
+
+	u := &UnsignedTransaction{
+		Version: FormatVersion,
+		Type:    TypeUnsigned,
+		ChainID: chainID.Uint64(),
+		From:    from.Hex(),
+		To:      tx.To().Hex(),
+		Nonce:   tx.Nonce(),
+		Gas:     tx.Gas(),
+		Value:   tx.Value().String(),
+		Data:    hexutil.Encode(tx.Data()),
+		Summary: summary,
+	}
+	if tx.Type() == types.LegacyTxType {
+		u.GasPrice = tx.GasPrice().String()
+	} else {
+		u.MaxPriorityFeePerGas = tx.GasTipCap().String()
+		u.MaxFeePerGas = tx.GasFeeCap().String()
+	}
+	return u, nil
+}
+
+// Transaction rebuilds the unsigned transaction, rejecting malformed or inconsistent files
+func (u *UnsignedTransaction) Transaction() (*types.Transaction, error) {
+	if u.Version != FormatVersion || u.Type != TypeUnsigned {
+		return nil, fmt.Errorf("not an unsigned transaction file (version %d, type %q)", u.Version, u.Type)
+	}
+	if u.ChainID == 0 {
+		return nil, errors.New("chainId is required")
+	}
+	if !common.IsHexAddress(u.From) || !common.IsHexAddress(u.To) {
+		return nil, errors.New("from and to must be addresses")
+	}
+
+	value, err := parseWei("value", u.Value)
+	if err != nil {
+		return nil, err
+	}
+	data, err := hexutil.Decode(u.Data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data: %w", err)
+	}
+	to := common.HexToAddress(u.To)
+
+	if u.GasPrice != "" {
+		if u.MaxFeePerGas != "" || u.MaxPriorityFeePerGas != "" {
+			return nil, errors.New("gasPrice cannot be combined with EIP-1559 fees")
+		}
+		gasPrice, err := parseWei("gasPrice", u.GasPrice)
+		if err != nil {
+			return nil, err
+		}
+		return types.NewTx(&types.LegacyTx{Nonce: u.Nonce, To: &to, Value: value, Gas: u.Gas, GasPrice: gasPrice, Data: data}), nil
+	}
+
+	tip, err := parseWei("maxPriorityFeePerGas", u.MaxPriorityFeePerGas)
+	if err != nil {
+		return nil, err
+	}
+	feeCap, err := parseWei("maxFeePerGas", u.MaxFeePerGas)
+	if err != nil {
+		return nil, err
+	}
+	if feeCap.Cmp(tip) < 0 {
+		return nil, errors.New("maxFeePerGas is below maxPriorityFeePerGas")
+	}
+	return types.NewTx(&types.DynamicFeeTx{
+		ChainID:   new(big.Int).SetUint64(u.ChainID),
+		Nonce:     u.Nonce,
+		To:        &to,
+		Value:     value,
+		Gas:       u.Gas,
+		GasTipCap: tip,
+		GasFeeCap: feeCap,
+		Data:      data,
+	}), nil
+}
+
+// Decoded describes what the transaction's fields actually do, independent of the summary
+func (u *UnsignedTransaction) Decoded() (string, error) {
+	tx, err := u.Transaction()
+	if err != nil {
+		return "", err
+	}
+
+	if len(tx.Data()) == 0 {
+		return fmt.Sprintf("send %s wei to %s", tx.Value(), tx.To().Hex()), nil
+	}
+	if len(tx.Data()) == 68 && bytes.Equal(tx.Data()[:4], transferSelector) {
+		recipient := common.BytesToAddress(tx.Data()[4:36])
+		amount := new(big.Int).SetBytes(tx.Data()[36:68])
+		return fmt.Sprintf("transfer %s token base units of %s to %s", amount, tx.To().Hex(), recipient.Hex()), nil
+	}
+	return fmt.Sprintf("call %s with %d bytes of data and %s wei", tx.To().Hex(), len(tx.Data()), tx.Value()), nil
+}
+
+// CheckSummary verifies that the summary describes what the transaction does: the recipient
+// it pays, the asset and the amount. A token is identified by the contract the transaction
+// calls, looked up in tokens, the registry of the transaction's chain; its symbol and decimals
+// come from there rather than from the file. tokens may be nil for Ether transfers.
+func (u *UnsignedTransaction) CheckSummary(tokens *registry.Registry) error {
+	tx, err := u.Transaction()
+	if err != nil {
+		return err
+	}
+
+	recipient, amount := *tx.To(), tx.Value()
+	symbol, decimals := "ETH", uint8(18)
+	if len(tx.Data()) == 68 && bytes.Equal(tx.Data()[:4], transferSelector) {
+		if tx.Value().Sign() != 0 {
+			return errors.New("token transfer also sends Ether")
+		}
+		recipient = common.BytesToAddress(tx.Data()[4:36])
+		amount = new(big.Int).SetBytes(tx.Data()[36:68])
+
+		token, err := lookupToken(tokens, u.ChainID, *tx.To())
+		if err != nil {
+			return err
+		}
+		symbol, decimals = token.Symbol, *token.Decimals
+	} else if len(tx.Data()) != 0 {
+		return errors.New("transaction calls a contract that is not an ERC-20 transfer")
+	}
+
+	if !common.IsHexAddress(u.Summary.Recipient) || common.HexToAddress(u.Summary.Recipient) != recipient {
+		return fmt.Errorf("summary recipient %s does not match the transaction recipient %s", u.Summary.Recipient, recipient.Hex())
+	}
+	if !strings.EqualFold(u.Summary.Asset, symbol) {
+		return fmt.Errorf("summary asset %s does not match the transferred asset %s", u.Summary.Asset, symbol)
+	}
+	summaryAmount, err := asset.ParseUnits(u.Summary.Amount, decimals)
+	if err != nil {
+		return fmt.Errorf("invalid summary amount: %w", err)
+	}
+	if summaryAmount.Cmp(amount) != 0 {
+		return fmt.Errorf("summary amount %s %s does not match the transferred amount %s %s",
+			u.Summary.Amount, u.Summary.Asset, asset.FormatUnits(amount, decimals), symbol)
+	}
+	return nil
+}
+
+// lookupToken finds the registry entry of the token contract a transfer calls
+func lookupToken(tokens *registry.Registry, chainID uint64, contract common.Address) (registry.Token, error) {
+	if tokens == nil {
+		return registry.Token{}, fmt.Errorf("no token registry to identify the token at %s", contract.Hex())
+	}
+	if tokens.ChainID != chainID {
+		return registry.Token{}, fmt.Errorf("token registry is for chain %d, transaction is for chain %d", tokens.ChainID, chainID)
+	}
+	token, ok := tokens.TokenByAddress(contract)
+	if !ok {
+		return registry.Token{}, fmt.Errorf("token %s is not in the %s token registry", contract.Hex(), tokens.Network)
+	}
+	if token.Decimals == nil {
+		return registry.Token{}, fmt.Errorf("token registry has no decimals for %s", token.Symbol)
+	}
+	return token, nil
+}
+
+// MaxFee is the most the transaction can cost in gas, in wei
+func (u *UnsignedTransaction) MaxFee() (*big.Int, error) {
+	tx, err := u.Transaction()
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).Mul(tx.GasFeeCap(), new(big.Int).SetUint64(tx.Gas())), nil
+}
+
+// Sign signs the transaction with privateKey, which must belong to From
+func Sign(u *UnsignedTransaction, privateKey *ecdsa.PrivateKey) (*SignedTransaction, error) {
+	tx, err := u.Transaction()
+	if err != nil {
+		return nil, err
+	}
+
+	signer := crypto.PubkeyToAddress(privateKey.PublicKey)
+	if signer != common.HexToAddress(u.From) {
+		return nil, fmt.Errorf("transaction is from %s, but the key belongs to %s", u.From, signer.Hex())
+	}
+
+	signedTx, err := transaction.SignTransaction(tx, new(big.Int).SetUint64(u.ChainID), privateKey)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := signedTx.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode signed transaction: %w", err)
+	}
+
+	return &SignedTransaction{
+		Version:        FormatVersion,
+		Type:           TypeSigned,
+		ChainID:        u.ChainID,
+		From:           signer.Hex(),
+		Nonce:          signedTx.Nonce(),
+		Hash:           signedTx.Hash().Hex(),
+		RawTransaction: hexutil.Encode(raw),
+		Summary:        u.Summary,
+	}, nil
+}
+
+// Transaction decodes the raw transaction and checks that it matches the file's chain,
+// sender and hash
+func (s *SignedTransaction) Transaction() (*types.Transaction, error) {
+	if s.Version != FormatVersion || s.Type != TypeSigned {
+		return nil, fmt.Errorf("not a signed transaction file (version %d, type %q)", s.Version, s.Type)
+	}
+
+	raw, err := hexutil.Decode(s.RawTransaction)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rawTransaction: %w", err)
+	}
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(raw); err != nil {
+		return nil, fmt.Errorf("failed to decode rawTransaction: %w", err)
+	}
+
+	if tx.ChainId().Uint64() != s.ChainID {
+		return nil, fmt.Errorf("rawTransaction is for chain %s, file says %d", tx.ChainId(), s.ChainID)
+	}
+	if tx.Hash().Hex() != s.Hash {
+		return nil, fmt.Errorf("rawTransaction hash %s does not match %s", tx.Hash().Hex(), s.Hash)
+	}
+	sender, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature: %w", err)
+	}
+	if sender != common.HexToAddress(s.From) {
+		return nil, fmt.Errorf("rawTransaction is signed by %s, file says %s", sender.Hex(), s.From)
+	}
+	return tx, nil
+}
+
+// SignedPath names the signed file written next to an unsigned one,
+// e.g. "payout.unsigned.json" becomes "payout.signed.json"
+func SignedPath(unsignedPath string) string {
+	base := strings.TrimSuffix(strings.TrimSuffix(unsignedPath, ".json"), ".unsigned")
+	return base + ".signed.json"
+}
+
+// WriteFile stores an unsigned or signed transaction as indented JSON
+func WriteFile(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode transaction file: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to write transaction file: %w", err)
+	}
+	return nil
+}
+
+func ReadUnsigned(path string) (*UnsignedTransaction, error) {
+	var u UnsignedTransaction
+	if err := readFile(path, &u); err != nil {
+		return nil, err
+	}
+	if _, err := u.Transaction(); err != nil {
+		return nil, fmt.Errorf("invalid transaction file %s: %w", path, err)
+	}
+	return &u, nil
+}
+
+func ReadSigned(path string) (*SignedTransaction, error) {
+	var s SignedTransaction
+	if err := readFile(path, &s); err != nil {
+		return nil, err
+	}
+	if _, err := s.Transaction(); err != nil {
+		return nil, fmt.Errorf("invalid transaction file %s: %w", path, err)
+	}
+	return &s, nil
+}
+
+func readFile(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read transaction file: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("failed to parse transaction file %s: %w", path, err)
+	}
+	return nil
+}
+
+func parseWei(field, value string) (*big.Int, error) {
+	wei, ok := new(big.Int).SetString(value, 10)
+	if !ok || wei.Sign() < 0 {
+		return nil, fmt.Errorf("invalid %s %q", field, value)
+	}
+	return wei, nil
+}
@@ -0,0 +1,224 @@
+// transfer/offline/offline_test.go
+
+package offline
+
+import (
+	"math/big"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"go-ethereum-wallet/transfer/registry"
+)
+
+var (
+	testChainID   = big.NewInt(11155111)
+	testRecipient = common.HexToAddress("0x000000000000000000000000000000000000dEaD")
+)
+
+func dynamicTransfer(t *testing.T, from common.Address) *UnsignedTransaction {
+	t.Helper()
+	tx := types.NewTx(&types.DynamicFeeTx{
+		ChainID:   testChainID,
+		Nonce:     7,
+		To:        &testRecipient,
+		Value:     big.NewInt(500_000_000_000_000_000),
+		Gas:       21000,
+		GasTipCap: big.NewInt(1_500_000_000),
+		GasFeeCap: big.NewInt(30_000_000_000),
+	})
+	u, err := NewUnsignedTransaction(tx, testChainID, from, Summary{Asset: "ETH", Amount: "0.5", Recipient: testRecipient.Hex()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func TestSignRoundTrip(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := crypto.PubkeyToAddress(key.PublicKey)
+
+	dir := t.TempDir()
+	unsignedPath := filepath.Join(dir, "payout.unsigned.json")
+	if err := WriteFile(unsignedPath, dynamicTransfer(t, from)); err != nil {
+		t.Fatal(err)
+	}
+
+	unsigned, err := ReadUnsigned(unsignedPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := unsigned.CheckSummary(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	signed, err := Sign(unsigned, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signedPath := SignedPath(unsignedPath)
+	if signedPath != filepath.Join(dir, "payout.signed.json") {
+		t.Errorf("signed path = %s", signedPath)
+	}
+	if err := WriteFile(signedPath, signed); err != nil {
+		t.Fatal(err)
+	}
+
+	read, err := ReadSigned(signedPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := read.Transaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.Nonce() != 7 || *tx.To() != testRecipient || tx.Value().String() != "500000000000000000" || tx.GasFeeCap().String() != "30000000000" {
+		t.Errorf("signed transaction fields changed: nonce %d, to %s, value %s", tx.Nonce(), tx.To().Hex(), tx.Value())
+	}
+}
+
+func TestSignLegacy(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := crypto.PubkeyToAddress(key.PublicKey)
+
+	tx := types.NewTx(&types.LegacyTx{Nonce: 1, To: &testRecipient, Value: big.NewInt(1), Gas: 21000, GasPrice: big.NewInt(20_000_000_000)})
+	unsigned, err := NewUnsignedTransaction(tx, testChainID, from, Summary{Asset: "ETH", Amount: "0.000000000000000001", Recipient: testRecipient.Hex()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unsigned.GasPrice != "20000000000" || unsigned.MaxFeePerGas != "" {
+		t.Errorf("legacy fees = %q/%q", unsigned.GasPrice, unsigned.MaxFeePerGas)
+	}
+
+	signed, err := Sign(unsigned, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signedTx, err := signed.Transaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signedTx.ChainId().Cmp(testChainID) != 0 {
+		t.Errorf("chain ID = %s, want %s", signedTx.ChainId(), testChainID)
+	}
+}
+
+func TestSignRejectsWrongKey(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+
+	if _, err := Sign(dynamicTransfer(t, crypto.PubkeyToAddress(key.PublicKey)), other); err == nil {
+		t.Error("expected error signing with a key that is not the sender's")
+	}
+}
+
+func TestCheckSummaryDetectsTampering(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	tests := []struct {
+		name   string
+		tamper func(*Summary)
+	}{
+		{"recipient", func(s *Summary) { s.Recipient = "0x9858EfFD232B4033E47d90003D41EC34EcaEda94" }},
+		{"amount", func(s *Summary) { s.Amount = "0.05" }},
+		{"asset", func(s *Summary) { s.Asset = "WETH" }},
+	}
+	for _, tt := range tests {
+		unsigned := dynamicTransfer(t, crypto.PubkeyToAddress(key.PublicKey))
+		tt.tamper(&unsigned.Summary)
+		if err := unsigned.CheckSummary(nil); err == nil {
+			t.Errorf("%s: expected error for a summary that differs from the transaction", tt.name)
+		}
+	}
+}
+
+var testToken = common.HexToAddress("0x1c7D4B196Cb0C7B01d743Fbc6116a902379C7238")
+
+func tokenTransfer(t *testing.T, summary Summary) *UnsignedTransaction {
+	t.Helper()
+	key, _ := crypto.GenerateKey()
+	data := append(append(append([]byte{}, transferSelector...), common.LeftPadBytes(testRecipient.Bytes(), 32)...), common.LeftPadBytes(big.NewInt(100_000_000).Bytes(), 32)...)
+
+	tx := types.NewTx(&types.DynamicFeeTx{ChainID: testChainID, To: &testToken, Gas: 50000, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(2), Data: data})
+	unsigned, err := NewUnsignedTransaction(tx, testChainID, crypto.PubkeyToAddress(key.PublicKey), summary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return unsigned
+}
+
+func testRegistry(chainID uint64) *registry.Registry {
+	six, eighteen := uint8(6), uint8(18)
+	return &registry.Registry{Network: "sepolia", ChainID: chainID, Tokens: []registry.Token{
+		{Symbol: "USDC", Address: testToken.Hex(), Decimals: &six},
+		{Symbol: "WETH", Address: "0xfFf9976782d46CC05630D1f6eBAb18b2324d6B14", Decimals: &eighteen},
+	}}
+}
+
+func TestERC20TransferDecoding(t *testing.T) {
+	unsigned := tokenTransfer(t, Summary{Asset: "USDC", Amount: "100", Recipient: testRecipient.Hex()})
+
+	if err := unsigned.CheckSummary(testRegistry(testChainID.Uint64())); err != nil {
+		t.Errorf("token transfer summary: %v", err)
+	}
+	decoded, err := unsigned.Decoded()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(decoded, "100000000") || !strings.Contains(decoded, testRecipient.Hex()) {
+		t.Errorf("decoded = %q", decoded)
+	}
+}
+
+func TestCheckSummaryVerifiesToken(t *testing.T) {
+	valid := Summary{Asset: "USDC", Amount: "100", Recipient: testRecipient.Hex()}
+	unknown := testRegistry(testChainID.Uint64())
+	unknown.Tokens = unknown.Tokens[1:]
+
+	tests := []struct {
+		name    string
+		summary Summary
+		tokens  *registry.Registry
+	}{
+		{"no registry", valid, nil},
+		{"other chain", valid, testRegistry(1)},
+		{"unknown contract", valid, unknown},
+		{"symbol of another token", Summary{Asset: "WETH", Amount: "100", Recipient: testRecipient.Hex()}, testRegistry(testChainID.Uint64())},
+		{"amount", Summary{Asset: "USDC", Amount: "1", Recipient: testRecipient.Hex()}, testRegistry(testChainID.Uint64())},
+		{"ether amount", Summary{Asset: "ETH", Amount: "0.0000000001", Recipient: testRecipient.Hex()}, testRegistry(testChainID.Uint64())},
+	}
+	for _, tt := range tests {
+		if err := tokenTransfer(t, tt.summary).CheckSummary(tt.tokens); err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+	}
+}
+
+func TestSignedTransactionRejectsMismatch(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	signed, err := Sign(dynamicTransfer(t, crypto.PubkeyToAddress(key.PublicKey)), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wrongSender := *signed
+	wrongSender.From = testRecipient.Hex()
+	wrongHash := *signed
+	wrongHash.Hash = common.Hash{}.Hex()
+	wrongChain := *signed
+	wrongChain.ChainID = 1
+
+	for name, s := range map[string]*SignedTransaction{"sender": &wrongSender, "hash": &wrongHash, "chain": &wrongChain} {
+		if _, err := s.Transaction(); err == nil {
+			t.Errorf("%s mismatch: expected error", name)
+		}
+	}
+}
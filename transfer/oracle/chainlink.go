@@ -0,0 +1,100 @@
+// transfer/oracle/chainlink.go
+
+package oracle
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+const aggregatorABI = `[
+	{"inputs":[],"name":"decimals","outputs":[{"name":"","type":"uint8"}],"stateMutability":"view","type":"function"},
+	{"inputs":[],"name":"latestRoundData","outputs":[{"name":"roundId","type":"uint80"},{"name":"answer","type":"int256"},{"name":"startedAt","type":"uint256"},{"name":"updatedAt","type":"uint256"},{"name":"answeredInRound","type":"uint80"}],"stateMutability":"view","type":"function"}
+]`
+
+// ChainlinkOracle reads Chainlink aggregator contracts through the RPC client.
+// Feeds maps a Pair such as "ETH/USD" to the aggregator address on the connected network.
+type ChainlinkOracle struct {
+	client      *ethclient.Client
+	feeds       map[string]common.Address
+	contractABI abi.ABI
+}
+
+func NewChainlinkOracle(client *ethclient.Client, feeds map[string]string) (*ChainlinkOracle, error) {
+	contractABI, err := abi.JSON(strings.NewReader(aggregatorABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ABI: %w", err)
+	}
+
+	addresses := make(map[string]common.Address, len(feeds))
+	for pair, address := range feeds {
+		if !common.IsHexAddress(address) {
+			return nil, fmt.Errorf("invalid Chainlink feed address %q for %s", address, pair)
+		}
+		addresses[strings.ToUpper(pair)] = common.HexToAddress(address)
+	}
+
+	return &ChainlinkOracle{client: client, feeds: addresses, contractABI: contractABI}, nil
+}
+
+func (c *ChainlinkOracle) Name() string {
+	return "Chainlink"
+}
+
+func (c *ChainlinkOracle) Price(ctx context.Context, base, quote string) (Price, error) {
+	feed, ok := c.feeds[Pair(base, quote)]
+	if !ok {
+		return Price{}, fmt.Errorf("%w: %s", ErrUnsupportedPair, Pair(base, quote))
+	}
+
+	decimalsOut, err := c.call(ctx, feed, "decimals")
+	if err != nil {
+		return Price{}, err
+	}
+	decimals := decimalsOut[0].(uint8)
+
+	round, err := c.call(ctx, feed, "latestRoundData")
+	if err != nil {
+		return Price{}, err
+	}
+	answer := round[1].(*big.Int)
+	updatedAt := round[3].(*big.Int)
+
+	if answer.Sign() <= 0 {
+		return Price{}, fmt.Errorf("Chainlink feed %s returned non-positive answer %s", feed.Hex(), answer)
+	}
+
+	value, _ := new(big.Rat).SetFrac(answer, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)).Float64()
+	return Price{
+		Value:     value,
+		UpdatedAt: time.Unix(updatedAt.Int64(), 0),
+		Source:    c.Name(),
+	}, nil
+}
+
+// call invokes a constant method of the aggregator and returns its unpacked outputs
+func (c *ChainlinkOracle) call(ctx context.Context, feed common.Address, method string) ([]interface{}, error) {
+	data, err := c.contractABI.Pack(method)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack %s call: %w", method, err)
+	}
+
+	result, err := c.client.CallContract(ctx, ethereum.CallMsg{To: &feed, Data: data}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call %s on %s: %w", method, feed.Hex(), err)
+	}
+
+	out, err := c.contractABI.Unpack(method, result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unpack %s from %s: %w", method, feed.Hex(), err)
+	}
+	return out, nil
+}
@@ -0,0 +1,69 @@
+// transfer/oracle/coinbase.go
+
+package oracle
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const coinbaseURL = "https://api.coinbase.com/v2/prices/%s-%s/spot"
+
+// CoinbaseOracle reads spot prices from the public Coinbase API
+type CoinbaseOracle struct {
+	URL    string // format string taking base and quote, defaults to the Coinbase spot endpoint
+	Client *http.Client
+}
+
+// NewCoinbaseOracle returns a Coinbase oracle whose requests give up after timeout
+func NewCoinbaseOracle(timeout time.Duration) *CoinbaseOracle {
+	return &CoinbaseOracle{
+		URL:    coinbaseURL,
+		Client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (c *CoinbaseOracle) Name() string {
+	return "Coinbase"
+}
+
+func (c *CoinbaseOracle) Price(ctx context.Context, base, quote string) (Price, error) {
+	url := fmt.Sprintf(c.URL, strings.ToUpper(base), strings.ToUpper(quote))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return Price{}, fmt.Errorf("failed to build request: %w", err)
+	}
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return Price{}, fmt.Errorf("failed to query Coinbase: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return Price{}, fmt.Errorf("%w: %s", ErrUnsupportedPair, Pair(base, quote))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Price{}, fmt.Errorf("Coinbase returned %s", resp.Status)
+	}
+
+	var result struct {
+		Data struct {
+			Amount string `json:"amount"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return Price{}, fmt.Errorf("failed to decode Coinbase response: %w", err)
+	}
+
+	value, err := strconv.ParseFloat(result.Data.Amount, 64)
+	if err != nil || value <= 0 {
+		return Price{}, fmt.Errorf("invalid Coinbase price %q", result.Data.Amount)
+	}
+	return Price{Value: value, UpdatedAt: time.Now(), Source: c.Name()}, nil
+}
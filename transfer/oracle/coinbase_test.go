@@ -0,0 +1,58 @@
+// transfer/oracle/coinbase_test.go
+
+package oracle
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCoinbaseOracle(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ETH-USD":
+			w.Write([]byte(`{"data":{"base":"ETH","currency":"USD","amount":"3456.78"}}`))
+		case "/ETH-EUR":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	c := NewCoinbaseOracle(time.Second)
+	c.URL = server.URL + "/%s-%s"
+
+	price, err := c.Price(context.Background(), "eth", "usd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if price.Value != 3456.78 {
+		t.Errorf("price = %f, want 3456.78", price.Value)
+	}
+
+	if _, err := c.Price(context.Background(), "ETH", "EUR"); err == nil {
+		t.Error("expected error for a non-200 response")
+	}
+	if _, err := c.Price(context.Background(), "FOO", "USD"); !errors.Is(err, ErrUnsupportedPair) {
+		t.Errorf("err = %v, want ErrUnsupportedPair", err)
+	}
+}
+
+func TestCoinbaseOracleTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	c := NewCoinbaseOracle(50 * time.Millisecond)
+	c.URL = server.URL + "/%s-%s"
+
+	if _, err := c.Price(context.Background(), "ETH", "USD"); err == nil {
+		t.Error("expected timeout error")
+	}
+}
@@ -0,0 +1,47 @@
+// transfer/oracle/cross.go
+
+package oracle
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// CrossOracle quotes every pair through an intermediate currency: Oracle prices the base
+// in Via, and Rates converts Via into the requested quote. This lets USD-only sources such as
+// Chainlink feeds and fixed stablecoin prices answer ETH/EUR or USDT/GBP.
+type CrossOracle struct {
+	Oracle PriceOracle
+	Rates  PriceOracle
+	Via    string
+}
+
+func (c *CrossOracle) Name() string {
+	return c.Oracle.Name() + " via " + strings.ToUpper(c.Via)
+}
+
+func (c *CrossOracle) Price(ctx context.Context, base, quote string) (Price, error) {
+	price, err := c.Oracle.Price(ctx, base, c.Via)
+	if err != nil {
+		return Price{}, err
+	}
+	if strings.EqualFold(quote, c.Via) {
+		return price, nil
+	}
+
+	rate, err := c.Rates.Price(ctx, c.Via, quote)
+	if err != nil {
+		return Price{}, fmt.Errorf("failed to convert %s to %s: %w", strings.ToUpper(c.Via), strings.ToUpper(quote), err)
+	}
+
+	updatedAt := price.UpdatedAt
+	if rate.UpdatedAt.Before(updatedAt) {
+		updatedAt = rate.UpdatedAt
+	}
+	return Price{
+		Value:     price.Value * rate.Value,
+		UpdatedAt: updatedAt,
+		Source:    price.Source + ", " + Pair(c.Via, quote) + " from " + rate.Source,
+	}, nil
+}
@@ -0,0 +1,103 @@
+// transfer/oracle/median.go
+
+package oracle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go-ethereum-wallet/transfer/logger"
+)
+
+// ErrPriceDeviation is returned when the sources of a MedianOracle disagree by more than MaxDeviation
+var ErrPriceDeviation = errors.New("price sources disagree")
+
+// MedianOracle queries every source and returns the median of the fresh quotes.
+// A failing quote or one older than MaxAge is discarded and logged, fewer than MinSources usable quotes is
+// an error, and a spread between the highest and lowest quote of more than MaxDeviation
+// (a fraction of the median, e.g. 0.02 for 2%) fails the whole lookup.
+type MedianOracle struct {
+	Sources      []PriceOracle
+	MinSources   int
+	MaxAge       time.Duration
+	MaxDeviation float64
+}
+
+func (m *MedianOracle) Name() string {
+	names := make([]string, len(m.Sources))
+	for i, source := range m.Sources {
+		names[i] = source.Name()
+	}
+	return "Median(" + strings.Join(names, ", ") + ")"
+}
+
+func (m *MedianOracle) Price(ctx context.Context, base, quote string) (Price, error) {
+	prices := make([]Price, len(m.Sources))
+	errs := make([]error, len(m.Sources))
+
+	var wg sync.WaitGroup
+	for i, source := range m.Sources {
+		wg.Add(1)
+		go func(i int, source PriceOracle) {
+			defer wg.Done()
+			prices[i], errs[i] = source.Price(ctx, base, quote)
+		}(i, source)
+	}
+	wg.Wait()
+
+	var fresh []Price
+	var failures []string
+	for i, source := range m.Sources {
+		switch {
+		case errs[i] != nil:
+			failures = append(failures, fmt.Sprintf("%s: %v", source.Name(), errs[i]))
+		case m.MaxAge > 0 && time.Since(prices[i].UpdatedAt) > m.MaxAge:
+			failures = append(failures, fmt.Sprintf("%s: stale price from %s", source.Name(), prices[i].UpdatedAt.Format(time.RFC3339)))
+		default:
+			fresh = append(fresh, prices[i])
+		}
+	}
+
+	minSources := m.MinSources
+	if minSources < 1 {
+		minSources = 1
+	}
+	if len(fresh) < minSources {
+		return Price{}, fmt.Errorf("%s: only %d of %d required sources available (%s)",
+			Pair(base, quote), len(fresh), minSources, strings.Join(failures, "; "))
+	}
+
+	sort.Slice(fresh, func(i, j int) bool { return fresh[i].Value < fresh[j].Value })
+	median := fresh[len(fresh)/2].Value
+	if len(fresh)%2 == 0 {
+		median = (fresh[len(fresh)/2-1].Value + fresh[len(fresh)/2].Value) / 2
+	}
+
+	if m.MaxDeviation > 0 {
+		low, high := fresh[0], fresh[len(fresh)-1]
+		if spread := (high.Value - low.Value) / median; spread > m.MaxDeviation {
+			return Price{}, fmt.Errorf("%w: %s quotes range from %f (%s) to %f (%s), a %.2f%% spread around the median %f",
+				ErrPriceDeviation, Pair(base, quote), low.Value, low.Source, high.Value, high.Source, spread*100, median)
+		}
+	}
+
+	// The price is still usable, but a lost source weakens the cross-check
+	for _, failure := range failures {
+		logger.Info.Printf("Ignoring %s price source %s\n", Pair(base, quote), failure)
+	}
+
+	sources := make([]string, len(fresh))
+	updatedAt := fresh[0].UpdatedAt
+	for i, price := range fresh {
+		sources[i] = price.Source
+		if price.UpdatedAt.Before(updatedAt) {
+			updatedAt = price.UpdatedAt
+		}
+	}
+	return Price{Value: median, UpdatedAt: updatedAt, Source: strings.Join(sources, ", ")}, nil
+}
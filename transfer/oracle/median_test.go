@@ -0,0 +1,115 @@
+// transfer/oracle/median_test.go
+
+package oracle
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"go-ethereum-wallet/transfer/logger"
+)
+
+func static(price float64) *StaticOracle {
+	return &StaticOracle{Prices: map[string]float64{Pair("ETH", "USD"): price}}
+}
+
+type failingOracle struct{}
+
+func (failingOracle) Name() string { return "Failing" }
+
+func (failingOracle) Price(ctx context.Context, base, quote string) (Price, error) {
+	return Price{}, errors.New("unavailable")
+}
+
+func TestMedianOracle(t *testing.T) {
+	tests := []struct {
+		name    string
+		sources []PriceOracle
+		want    float64
+	}{
+		{"single", []PriceOracle{static(2000)}, 2000},
+		{"odd", []PriceOracle{static(2010), static(2000), static(2005)}, 2005},
+		{"even", []PriceOracle{static(2000), static(2010)}, 2005},
+		{"skips failures", []PriceOracle{failingOracle{}, static(2000)}, 2000},
+	}
+	for _, tt := range tests {
+		m := &MedianOracle{Sources: tt.sources, MaxAge: time.Minute, MaxDeviation: 0.02}
+		price, err := m.Price(context.Background(), "eth", "usd")
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if price.Value != tt.want {
+			t.Errorf("%s: price = %f, want %f", tt.name, price.Value, tt.want)
+		}
+	}
+}
+
+func TestMedianOracleDeviation(t *testing.T) {
+	m := &MedianOracle{Sources: []PriceOracle{static(2000), static(2100)}, MaxDeviation: 0.02}
+	if _, err := m.Price(context.Background(), "ETH", "USD"); !errors.Is(err, ErrPriceDeviation) {
+		t.Errorf("err = %v, want ErrPriceDeviation", err)
+	}
+
+	m.MaxDeviation = 0.05
+	if _, err := m.Price(context.Background(), "ETH", "USD"); err != nil {
+		t.Errorf("within 5%%: %v", err)
+	}
+
+	// Every quote is within 1.5% of the median, but the outer two are 3% apart
+	m = &MedianOracle{Sources: []PriceOracle{static(2000), static(2030), static(2060)}, MaxDeviation: 0.02}
+	if _, err := m.Price(context.Background(), "ETH", "USD"); !errors.Is(err, ErrPriceDeviation) {
+		t.Errorf("3%% spread: err = %v, want ErrPriceDeviation", err)
+	}
+}
+
+func TestMedianOracleLogsDroppedSource(t *testing.T) {
+	var out bytes.Buffer
+	logger.Info.SetOutput(&out)
+	defer logger.Info.SetOutput(os.Stdout)
+
+	m := &MedianOracle{Sources: []PriceOracle{failingOracle{}, static(2000)}, MinSources: 1}
+	if _, err := m.Price(context.Background(), "ETH", "USD"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "Failing: unavailable") {
+		t.Errorf("log = %q, want the dropped source", out.String())
+	}
+}
+
+func TestMedianOracleStale(t *testing.T) {
+	stale := static(1500)
+	stale.UpdatedAt = time.Now().Add(-2 * time.Hour)
+
+	m := &MedianOracle{Sources: []PriceOracle{stale, static(2000)}, MaxAge: time.Hour, MaxDeviation: 0.02}
+	price, err := m.Price(context.Background(), "ETH", "USD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if price.Value != 2000 {
+		t.Errorf("price = %f, want the fresh quote 2000", price.Value)
+	}
+
+	m.MinSources = 2
+	if _, err := m.Price(context.Background(), "ETH", "USD"); err == nil {
+		t.Error("expected error with one fresh source and MinSources 2")
+	}
+}
+
+func TestMedianOracleNoSources(t *testing.T) {
+	m := &MedianOracle{Sources: []PriceOracle{failingOracle{}}}
+	if _, err := m.Price(context.Background(), "ETH", "USD"); err == nil {
+		t.Error("expected error when every source fails")
+	}
+}
+
+func TestStaticOracleUnsupportedPair(t *testing.T) {
+	if _, err := static(2000).Price(context.Background(), "BTC", "USD"); !errors.Is(err, ErrUnsupportedPair) {
+		t.Errorf("err = %v, want ErrUnsupportedPair", err)
+	}
+}
@@ -0,0 +1,31 @@
+// transfer/oracle/oracle.go
+
+package oracle
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrUnsupportedPair is returned by an oracle that has no quote for the requested pair
+var ErrUnsupportedPair = errors.New("unsupported price pair")
+
+// Price is the value of one whole base unit in the quote currency
+type Price struct {
+	Value     float64
+	UpdatedAt time.Time
+	Source    string
+}
+
+// PriceOracle quotes the price of a base asset, e.g. ETH, in a quote currency, e.g. USD
+type PriceOracle interface {
+	Name() string
+	Price(ctx context.Context, base, quote string) (Price, error)
+}
+
+// Pair formats a base and quote as the "ETH/USD" key used by the oracles' price tables
+func Pair(base, quote string) string {
+	return strings.ToUpper(base) + "/" + strings.ToUpper(quote)
+}
@@ -0,0 +1,44 @@
+// transfer/oracle/route.go
+
+package oracle
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// RoutedOracle sends each lookup to the oracle registered for its base symbol,
+// and to Fallback for symbols without a route
+type RoutedOracle struct {
+	Routes   map[string]PriceOracle
+	Fallback PriceOracle
+}
+
+func (r *RoutedOracle) Name() string {
+	return "Routed"
+}
+
+func (r *RoutedOracle) Price(ctx context.Context, base, quote string) (Price, error) {
+	if source, ok := r.Routes[strings.ToUpper(base)]; ok {
+		return source.Price(ctx, base, quote)
+	}
+	if r.Fallback == nil {
+		return Price{}, fmt.Errorf("%w: no price source for %s", ErrUnsupportedPair, Pair(base, quote))
+	}
+	return r.Fallback.Price(ctx, base, quote)
+}
+
+// AliasOracle prices one asset as another, e.g. WETH as ETH or a token under its exchange symbol
+type AliasOracle struct {
+	Oracle PriceOracle
+	Base   string
+}
+
+func (a *AliasOracle) Name() string {
+	return a.Oracle.Name()
+}
+
+func (a *AliasOracle) Price(ctx context.Context, base, quote string) (Price, error) {
+	return a.Oracle.Price(ctx, a.Base, quote)
+}
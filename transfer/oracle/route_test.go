@@ -0,0 +1,80 @@
+// transfer/oracle/route_test.go
+
+package oracle
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRoutedOracle(t *testing.T) {
+	eth := static(2000)
+	fallback := &StaticOracle{Prices: map[string]float64{Pair("DAI", "USD"): 0.999}}
+
+	r := &RoutedOracle{
+		Routes: map[string]PriceOracle{
+			"ETH":  eth,
+			"WETH": &AliasOracle{Oracle: eth, Base: "ETH"},
+			"USDT": &StaticOracle{Prices: map[string]float64{Pair("USDT", "USD"): 1}},
+		},
+		Fallback: fallback,
+	}
+
+	tests := []struct {
+		base string
+		want float64
+	}{
+		{"ETH", 2000},
+		{"weth", 2000},
+		{"USDT", 1},
+		{"DAI", 0.999},
+	}
+	for _, tt := range tests {
+		price, err := r.Price(context.Background(), tt.base, "USD")
+		if err != nil {
+			t.Errorf("%s: %v", tt.base, err)
+			continue
+		}
+		if price.Value != tt.want {
+			t.Errorf("%s: price = %f, want %f", tt.base, price.Value, tt.want)
+		}
+	}
+
+	if _, err := r.Price(context.Background(), "FOO", "USD"); !errors.Is(err, ErrUnsupportedPair) {
+		t.Errorf("err = %v, want ErrUnsupportedPair", err)
+	}
+
+	r.Fallback = nil
+	if _, err := r.Price(context.Background(), "DAI", "USD"); !errors.Is(err, ErrUnsupportedPair) {
+		t.Errorf("without fallback: err = %v, want ErrUnsupportedPair", err)
+	}
+}
+
+func TestCrossOracle(t *testing.T) {
+	rates := &StaticOracle{Prices: map[string]float64{Pair("USD", "EUR"): 0.9, Pair("USD", "GBP"): 0.8}}
+	c := &CrossOracle{Oracle: static(2000), Rates: rates, Via: "USD"}
+
+	tests := []struct {
+		quote string
+		want  float64
+	}{
+		{"USD", 2000},
+		{"eur", 1800},
+		{"GBP", 1600},
+	}
+	for _, tt := range tests {
+		price, err := c.Price(context.Background(), "ETH", tt.quote)
+		if err != nil {
+			t.Errorf("%s: %v", tt.quote, err)
+			continue
+		}
+		if price.Value != tt.want {
+			t.Errorf("ETH/%s = %f, want %f", tt.quote, price.Value, tt.want)
+		}
+	}
+
+	if _, err := c.Price(context.Background(), "ETH", "JPY"); !errors.Is(err, ErrUnsupportedPair) {
+		t.Errorf("err = %v, want ErrUnsupportedPair", err)
+	}
+}
@@ -0,0 +1,33 @@
+// transfer/oracle/static.go
+
+package oracle
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// StaticOracle returns fixed prices keyed by Pair, for tests and pegged assets.
+// A zero UpdatedAt reports the prices as current.
+type StaticOracle struct {
+	Prices    map[string]float64
+	UpdatedAt time.Time
+}
+
+func (s *StaticOracle) Name() string {
+	return "Static"
+}
+
+func (s *StaticOracle) Price(ctx context.Context, base, quote string) (Price, error) {
+	value, ok := s.Prices[Pair(base, quote)]
+	if !ok {
+		return Price{}, fmt.Errorf("%w: %s", ErrUnsupportedPair, Pair(base, quote))
+	}
+
+	updatedAt := s.UpdatedAt
+	if updatedAt.IsZero() {
+		updatedAt = time.Now()
+	}
+	return Price{Value: value, UpdatedAt: updatedAt, Source: s.Name()}, nil
+}
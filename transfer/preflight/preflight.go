@@ -0,0 +1,158 @@
+// transfer/preflight/preflight.go
+
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"go-ethereum-wallet/transfer/asset"
+)
+
+// Backend reads the native and token balances of the sender
+type Backend interface {
+	ethereum.ContractCaller
+	BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error)
+}
+
+// tokenBalance is implemented by assets whose balance lives in a contract, such as ERC-20 tokens
+type tokenBalance interface {
+	BalanceOf(ctx context.Context, caller ethereum.ContractCaller, owner common.Address) (*big.Int, error)
+}
+
+// Requirement is an amount in base units of one asset the sender must hold.
+// Price is the fiat price of one whole unit and only used to report shortfalls.
+type Requirement struct {
+	Asset  asset.Asset
+	Amount *big.Int
+	Price  float64
+}
+
+// ForTransaction lists what tx needs: ETH for its value plus its maximum fee and, for a
+// token transfer, amount of the token
+func ForTransaction(a asset.Asset, tx *types.Transaction, amount *big.Int, assetPrice, ethPrice float64) []Requirement {
+	requirements := []Requirement{{Asset: &asset.Ether{}, Amount: tx.Cost(), Price: ethPrice}}
+	if _, ok := a.(tokenBalance); ok {
+		requirements = append(requirements, Requirement{Asset: a, Amount: amount, Price: assetPrice})
+	}
+	return requirements
+}
+
+// ForInput lists what a transfer built from input needs before its gas limit is estimated,
+// taking input.GasLimit as the gas limit
+func ForInput(a asset.Asset, input *asset.TransferInput) []Requirement {
+	if _, ok := a.(tokenBalance); ok {
+		return []Requirement{
+			{Asset: &asset.Ether{}, Amount: input.MaxFee(), Price: input.EthPrice},
+			{Asset: a, Amount: input.Amount, Price: input.AssetPrice},
+		}
+	}
+	native := new(big.Int).Add(input.MaxFee(), input.Amount)
+	return []Requirement{{Asset: &asset.Ether{}, Amount: native, Price: input.EthPrice}}
+}
+
+// Shortfall is an asset the sender holds too little of
+type Shortfall struct {
+	Symbol    string
+	Decimals  uint8
+	Price     float64
+	Required  *big.Int
+	Available *big.Int
+}
+
+// Missing is how much more the sender needs, in base units
+func (s Shortfall) Missing() *big.Int {
+	return new(big.Int).Sub(s.Required, s.Available)
+}
+
+// Format renders the shortfall in units and, when a price is known, in fiat, e.g.
+// "USDT: need 250 USDT (250.00 USD), have 100 USDT (100.00 USD), short 150 USDT (150.00 USD)"
+func (s Shortfall) Format(currency string) string {
+	return fmt.Sprintf("%s: need %s, have %s, short %s", s.Symbol,
+		s.value(s.Required, currency), s.value(s.Available, currency), s.value(s.Missing(), currency))
+}
+
+func (s Shortfall) value(amount *big.Int, currency string) string {
+	units := asset.FormatUnits(amount, s.Decimals) + " " + s.Symbol
+	if s.Price <= 0 {
+		return units
+	}
+	fiat, err := asset.UnitsToFiat(amount, s.Price, s.Decimals)
+	if err != nil {
+		return units
+	}
+	return fmt.Sprintf("%s (%s)", units, asset.FormatFiat(fiat, currency, 2))
+}
+
+// InsufficientFundsError lists every asset the sender is short of
+type InsufficientFundsError struct {
+	Shortfalls []Shortfall
+	Currency   string
+}
+
+func (e *InsufficientFundsError) Error() string {
+	lines := make([]string, len(e.Shortfalls))
+	for i, shortfall := range e.Shortfalls {
+		lines[i] = shortfall.Format(e.Currency)
+	}
+	return "insufficient funds: " + strings.Join(lines, "; ")
+}
+
+// Check compares the balances of owner with the requirements, adding up requirements for the
+// same asset. It returns an *InsufficientFundsError listing every shortfall.
+func Check(ctx context.Context, backend Backend, owner common.Address, currency string, requirements ...Requirement) error {
+	var order []string
+	totals := make(map[string]*Requirement)
+	for _, r := range requirements {
+		symbol := strings.ToUpper(r.Asset.Symbol())
+		if total, ok := totals[symbol]; ok {
+			total.Amount = new(big.Int).Add(total.Amount, r.Amount)
+			continue
+		}
+		totals[symbol] = &Requirement{Asset: r.Asset, Amount: new(big.Int).Set(r.Amount), Price: r.Price}
+		order = append(order, symbol)
+	}
+
+	var shortfalls []Shortfall
+	for _, symbol := range order {
+		r := totals[symbol]
+		if r.Amount.Sign() <= 0 {
+			continue
+		}
+		available, err := balance(ctx, backend, owner, r.Asset)
+		if err != nil {
+			return err
+		}
+		if available.Cmp(r.Amount) < 0 {
+			shortfalls = append(shortfalls, Shortfall{
+				Symbol:    symbol,
+				Decimals:  r.Asset.Decimals(),
+				Price:     r.Price,
+				Required:  r.Amount,
+				Available: available,
+			})
+		}
+	}
+
+	if len(shortfalls) > 0 {
+		return &InsufficientFundsError{Shortfalls: shortfalls, Currency: currency}
+	}
+	return nil
+}
+
+func balance(ctx context.Context, backend Backend, owner common.Address, a asset.Asset) (*big.Int, error) {
+	if token, ok := a.(tokenBalance); ok {
+		return token.BalanceOf(ctx, backend, owner)
+	}
+	available, err := backend.BalanceAt(ctx, owner, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get balance of %s: %w", owner.Hex(), err)
+	}
+	return available, nil
+}
@@ -0,0 +1,132 @@
+// transfer/preflight/preflight_test.go
+
+package preflight
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"go-ethereum-wallet/transfer/asset"
+)
+
+type fakeBackend struct {
+	ether  *big.Int
+	tokens *big.Int
+	calls  int
+}
+
+func (b *fakeBackend) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	return b.ether, nil
+}
+
+func (b *fakeBackend) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	b.calls++
+	return common.LeftPadBytes(b.tokens.Bytes(), 32), nil
+}
+
+var owner = common.HexToAddress("0x00000000000000000000000000000000000000aa")
+
+func newToken(t *testing.T) *asset.ERC20 {
+	t.Helper()
+	token, err := asset.NewERC20WithMetadata("0xdAC17F958D2ee523a2206206994597C13D831ec7", "Tether USD", "USDT", 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func ether(whole int64) *big.Int {
+	return new(big.Int).Mul(big.NewInt(whole), big.NewInt(1e18))
+}
+
+func TestForTransaction(t *testing.T) {
+	to := common.HexToAddress("0x00000000000000000000000000000000000000bb")
+	tx := types.NewTx(&types.DynamicFeeTx{Gas: 21000, GasFeeCap: big.NewInt(2e9), GasTipCap: big.NewInt(1e9), To: &to, Value: ether(1)})
+
+	requirements := ForTransaction(&asset.Ether{}, tx, ether(1), 3000, 3000)
+	if len(requirements) != 1 {
+		t.Fatalf("got %d requirements for an ETH transfer, want 1", len(requirements))
+	}
+	want := new(big.Int).Add(ether(1), big.NewInt(21000*2e9))
+	if requirements[0].Amount.Cmp(want) != 0 {
+		t.Errorf("ETH required = %s, want value plus max fee %s", requirements[0].Amount, want)
+	}
+
+	tokenTx := types.NewTx(&types.DynamicFeeTx{Gas: 65000, GasFeeCap: big.NewInt(2e9), GasTipCap: big.NewInt(1e9), To: &to, Value: new(big.Int)})
+	requirements = ForTransaction(newToken(t), tokenTx, big.NewInt(250e6), 1, 3000)
+	if len(requirements) != 2 || requirements[1].Asset.Symbol() != "USDT" || requirements[1].Amount.Cmp(big.NewInt(250e6)) != 0 {
+		t.Errorf("token requirements = %+v", requirements)
+	}
+	if requirements[0].Amount.Cmp(big.NewInt(65000*2e9)) != 0 {
+		t.Errorf("ETH required for a token transfer = %s, want the max fee only", requirements[0].Amount)
+	}
+}
+
+func TestCheckSufficient(t *testing.T) {
+	backend := &fakeBackend{ether: ether(2), tokens: big.NewInt(300e6)}
+	err := Check(context.Background(), backend, owner, "USD",
+		Requirement{Asset: &asset.Ether{}, Amount: ether(1), Price: 3000},
+		Requirement{Asset: newToken(t), Amount: big.NewInt(250e6), Price: 1},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if backend.calls != 1 {
+		t.Errorf("balanceOf called %d times, want 1", backend.calls)
+	}
+}
+
+func TestCheckReportsEveryShortfall(t *testing.T) {
+	backend := &fakeBackend{ether: ether(1), tokens: big.NewInt(100e6)}
+	token := newToken(t)
+	err := Check(context.Background(), backend, owner, "USD",
+		Requirement{Asset: &asset.Ether{}, Amount: ether(1), Price: 3000},
+		Requirement{Asset: token, Amount: big.NewInt(150e6), Price: 1},
+		Requirement{Asset: &asset.Ether{}, Amount: big.NewInt(5e17), Price: 3000},
+		Requirement{Asset: token, Amount: big.NewInt(100e6), Price: 1},
+	)
+
+	var insufficient *InsufficientFundsError
+	if !errors.As(err, &insufficient) {
+		t.Fatalf("err = %v, want *InsufficientFundsError", err)
+	}
+	if len(insufficient.Shortfalls) != 2 {
+		t.Fatalf("got %d shortfalls, want 2", len(insufficient.Shortfalls))
+	}
+
+	want := "insufficient funds: " +
+		"ETH: need 1.5 ETH (4500.00 USD), have 1 ETH (3000.00 USD), short 0.5 ETH (1500.00 USD); " +
+		"USDT: need 250 USDT (250.00 USD), have 100 USDT (100.00 USD), short 150 USDT (150.00 USD)"
+	if err.Error() != want {
+		t.Errorf("error =\n%s\nwant\n%s", err, want)
+	}
+}
+
+func TestShortfallWithoutPrice(t *testing.T) {
+	s := Shortfall{Symbol: "ETH", Decimals: 18, Required: ether(2), Available: ether(1)}
+	if got, want := s.Format("USD"), "ETH: need 2 ETH, have 1 ETH, short 1 ETH"; got != want {
+		t.Errorf("Format = %q, want %q", got, want)
+	}
+}
+
+func TestForInput(t *testing.T) {
+	input := &asset.TransferInput{Amount: big.NewInt(250e6), GasLimit: 21000, GasTipCap: big.NewInt(1e9), GasFeeCap: big.NewInt(2e9), AssetPrice: 1, EthPrice: 3000}
+
+	requirements := ForInput(newToken(t), input)
+	if len(requirements) != 2 || requirements[0].Amount.Cmp(big.NewInt(21000*2e9)) != 0 || requirements[1].Amount.Cmp(big.NewInt(250e6)) != 0 {
+		t.Errorf("token requirements = %+v", requirements)
+	}
+
+	input.Amount = ether(1)
+	requirements = ForInput(&asset.Ether{}, input)
+	want := new(big.Int).Add(ether(1), big.NewInt(21000*2e9))
+	if len(requirements) != 1 || requirements[0].Amount.Cmp(want) != 0 {
+		t.Errorf("ETH requirements = %+v, want value plus max fee %s", requirements, want)
+	}
+}
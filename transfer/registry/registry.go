@@ -0,0 +1,230 @@
+// transfer/registry/registry.go
+
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"go-ethereum-wallet/transfer/asset"
+	"go-ethereum-wallet/transfer/oracle"
+)
+
+// Price source kinds. A price source is written as "<kind>" or "<kind>:<value>".
+const (
+	// PriceSourceFixed is a constant USD price, e.g. "fixed:1" for stablecoins
+	PriceSourceFixed = "fixed"
+	// PriceSourceCoinbase is a Coinbase spot symbol, e.g. "coinbase:DAI"
+	PriceSourceCoinbase = "coinbase"
+	// PriceSourceChainlink is a Chainlink aggregator address, e.g. "chainlink:0x..."
+	PriceSourceChainlink = "chainlink"
+	// PriceSourceEth tracks the ETH price, e.g. for WETH
+	PriceSourceEth = "eth"
+)
+
+// Token is a registry entry. Decimals is read from the chain when omitted.
+// MaxPriceAge is written as a duration such as "25h" and overrides the network's maximum price
+// age for a "chainlink" price source, whose feeds can have a longer heartbeat than the ETH feed.
+type Token struct {
+	Symbol      string `json:"symbol"`
+	Name        string `json:"name,omitempty"`
+	Address     string `json:"address"`
+	Decimals    *uint8 `json:"decimals,omitempty"`
+	PriceSource string `json:"priceSource,omitempty"`
+	MaxPriceAge string `json:"maxPriceAge,omitempty"`
+}
+
+// priceAge returns the token's maximum price age, or fallback when it sets none
+func (t Token) priceAge(fallback time.Duration) (time.Duration, error) {
+	if t.MaxPriceAge == "" {
+		return fallback, nil
+	}
+	age, err := time.ParseDuration(t.MaxPriceAge)
+	if err != nil || age <= 0 {
+		return 0, fmt.Errorf("invalid maxPriceAge %q: must be a positive duration such as \"25h\"", t.MaxPriceAge)
+	}
+	return age, nil
+}
+
+// Registry lists the tokens available on one network
+type Registry struct {
+	Network string  `json:"network"`
+	ChainID uint64  `json:"chainId"`
+	Tokens  []Token `json:"tokens"`
+}
+
+// Load reads and validates a registry file
+func Load(path string) (*Registry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token registry: %w", err)
+	}
+
+	var registry Registry
+	if err := json.Unmarshal(data, &registry); err != nil {
+		return nil, fmt.Errorf("failed to parse token registry %s: %w", path, err)
+	}
+
+	if err := registry.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid token registry %s: %w", path, err)
+	}
+	return &registry, nil
+}
+
+// Validate checks that every token has a unique symbol, a valid address and a known price source;
+// a fixed price must be positive, and only a "chainlink" price source may set a maximum price age
+func (r *Registry) Validate() error {
+	symbols := make(map[string]bool)
+	for i, token := range r.Tokens {
+		if token.Symbol == "" {
+			return fmt.Errorf("token %d has no symbol", i)
+		}
+		key := strings.ToUpper(token.Symbol)
+		if symbols[key] {
+			return fmt.Errorf("duplicate token symbol %s", token.Symbol)
+		}
+		symbols[key] = true
+
+		if !common.IsHexAddress(token.Address) {
+			return fmt.Errorf("token %s has invalid address %q", token.Symbol, token.Address)
+		}
+		kind := ""
+		if token.PriceSource != "" {
+			var err error
+			if kind, _, err = ParsePriceSource(token.PriceSource); err != nil {
+				return fmt.Errorf("token %s: %w", token.Symbol, err)
+			}
+		}
+		if token.MaxPriceAge != "" {
+			if kind != PriceSourceChainlink {
+				return fmt.Errorf("token %s: maxPriceAge needs a chainlink price source", token.Symbol)
+			}
+			if _, err := token.priceAge(0); err != nil {
+				return fmt.Errorf("token %s: %w", token.Symbol, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Token looks up a token by symbol, ignoring case
+func (r *Registry) Token(symbol string) (Token, bool) {
+	for _, token := range r.Tokens {
+		if strings.EqualFold(token.Symbol, symbol) {
+			return token, true
+		}
+	}
+	return Token{}, false
+}
+
+// TokenByAddress looks up a token by contract address
+func (r *Registry) TokenByAddress(address common.Address) (Token, bool) {
+	for _, token := range r.Tokens {
+		if common.HexToAddress(token.Address) == address {
+			return token, true
+		}
+	}
+	return Token{}, false
+}
+
+// Assets builds the asset menu: Ether first, then every registry token in file order.
+// Tokens without decimals in the registry read their metadata from the chain.
+func (r *Registry) Assets(client *ethclient.Client) (map[string]asset.Asset, error) {
+	assets := map[string]asset.Asset{
+		"1": &asset.Ether{},
+	}
+
+	for _, token := range r.Tokens {
+		var tokenAsset asset.Asset
+		var err error
+		if token.Decimals != nil {
+			tokenAsset, err = asset.NewERC20WithMetadata(token.Address, token.Name, token.Symbol, *token.Decimals)
+		} else {
+			tokenAsset, err = asset.NewERC20(client, token.Address)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to load token %s: %w", token.Symbol, err)
+		}
+		assets[strconv.Itoa(len(assets)+1)] = tokenAsset
+	}
+	return assets, nil
+}
+
+// PriceOracle routes price lookups for Ether and every registry token to the token's price source.
+// ETH and "eth" sources are quoted by eth; tokens without a price source, and "coinbase" sources,
+// are quoted by exchange under their symbol. A "chainlink" answer older than the token's
+// MaxPriceAge, or maxAge when it sets none, is rejected like the ETH feed; 0 accepts any age.
+func (r *Registry) PriceOracle(client *ethclient.Client, eth, exchange oracle.PriceOracle, maxAge time.Duration) (oracle.PriceOracle, error) {
+	routes := map[string]oracle.PriceOracle{
+		"ETH": eth,
+	}
+
+	for _, token := range r.Tokens {
+		if token.PriceSource == "" {
+			continue
+		}
+
+		kind, value, err := ParsePriceSource(token.PriceSource)
+		if err != nil {
+			return nil, fmt.Errorf("token %s: %w", token.Symbol, err)
+		}
+
+		var source oracle.PriceOracle
+		switch kind {
+		case PriceSourceEth:
+			source = &oracle.AliasOracle{Oracle: eth, Base: "ETH"}
+		case PriceSourceFixed:
+			price, _ := strconv.ParseFloat(value, 64)
+			source = &oracle.StaticOracle{Prices: map[string]float64{oracle.Pair(token.Symbol, "USD"): price}}
+		case PriceSourceCoinbase:
+			source = &oracle.AliasOracle{Oracle: exchange, Base: value}
+		case PriceSourceChainlink:
+			age, err := token.priceAge(maxAge)
+			if err != nil {
+				return nil, fmt.Errorf("token %s: %w", token.Symbol, err)
+			}
+			feed, err := oracle.NewChainlinkOracle(client, map[string]string{oracle.Pair(token.Symbol, "USD"): value})
+			if err != nil {
+				return nil, fmt.Errorf("token %s: %w", token.Symbol, err)
+			}
+			source = &oracle.MedianOracle{Sources: []oracle.PriceOracle{feed}, MinSources: 1, MaxAge: age}
+		}
+		routes[strings.ToUpper(token.Symbol)] = source
+	}
+
+	return &oracle.RoutedOracle{Routes: routes, Fallback: exchange}, nil
+}
+
+// ParsePriceSource splits a price source into its kind and value
+func ParsePriceSource(source string) (string, string, error) {
+	kind, value, _ := strings.Cut(source, ":")
+	switch kind {
+	case PriceSourceEth:
+		if value != "" {
+			return "", "", fmt.Errorf("price source %q takes no value", source)
+		}
+	case PriceSourceFixed:
+		price, err := strconv.ParseFloat(value, 64)
+		if err != nil || !(price > 0) || math.IsInf(price, 1) {
+			return "", "", fmt.Errorf("invalid fixed price in %q: must be a positive number", source)
+		}
+	case PriceSourceCoinbase:
+		if value == "" {
+			return "", "", fmt.Errorf("price source %q needs a symbol", source)
+		}
+	case PriceSourceChainlink:
+		if !common.IsHexAddress(value) {
+			return "", "", fmt.Errorf("invalid Chainlink feed address in %q", source)
+		}
+	default:
+		return "", "", fmt.Errorf("unknown price source %q", source)
+	}
+	return kind, value, nil
+}
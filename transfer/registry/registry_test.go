@@ -0,0 +1,232 @@
+// transfer/registry/registry_test.go
+
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"go-ethereum-wallet/transfer/oracle"
+)
+
+const (
+	testUSDC = "0x1c7D4B196Cb0C7B01d743Fbc6116a902379C7238"
+	testWETH = "0xfFf9976782d46CC05630D1f6eBAb18b2324d6B14"
+)
+
+func decimals(d uint8) *uint8 {
+	return &d
+}
+
+func TestParsePriceSource(t *testing.T) {
+	tests := []struct {
+		source    string
+		wantKind  string
+		wantValue string
+		wantErr   bool
+	}{
+		{"fixed:1", PriceSourceFixed, "1", false},
+		{"fixed:0.998", PriceSourceFixed, "0.998", false},
+		{"fixed:0", "", "", true},
+		{"fixed:-1", "", "", true},
+		{"fixed:NaN", "", "", true},
+		{"fixed:Inf", "", "", true},
+		{"fixed:", "", "", true},
+		{"fixed:one", "", "", true},
+		{"coinbase:DAI", PriceSourceCoinbase, "DAI", false},
+		{"coinbase", "", "", true},
+		{"chainlink:" + testWETH, PriceSourceChainlink, testWETH, false},
+		{"chainlink:0x1234", "", "", true},
+		{"eth", PriceSourceEth, "", false},
+		{"eth:1", "", "", true},
+		{"uniswap:DAI", "", "", true},
+	}
+	for _, tt := range tests {
+		kind, value, err := ParsePriceSource(tt.source)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q: err = %v, want error %v", tt.source, err, tt.wantErr)
+			continue
+		}
+		if kind != tt.wantKind || value != tt.wantValue {
+			t.Errorf("%q: got %q, %q; want %q, %q", tt.source, kind, value, tt.wantKind, tt.wantValue)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		tokens  []Token
+		wantErr bool
+	}{
+		{"empty", nil, false},
+		{"valid", []Token{
+			{Symbol: "USDC", Address: testUSDC, Decimals: decimals(6), PriceSource: "fixed:1"},
+			{Symbol: "WETH", Address: testWETH, PriceSource: "eth"},
+		}, false},
+		{"no symbol", []Token{{Address: testUSDC}}, true},
+		{"duplicate symbol ignoring case", []Token{
+			{Symbol: "USDC", Address: testUSDC},
+			{Symbol: "usdc", Address: testWETH},
+		}, true},
+		{"invalid address", []Token{{Symbol: "USDC", Address: "0x1234"}}, true},
+		{"zero fixed price", []Token{{Symbol: "USDC", Address: testUSDC, PriceSource: "fixed:0"}}, true},
+		{"negative fixed price", []Token{{Symbol: "USDC", Address: testUSDC, PriceSource: "fixed:-1"}}, true},
+		{"unknown price source", []Token{{Symbol: "USDC", Address: testUSDC, PriceSource: "oracle"}}, true},
+		{"chainlink price age", []Token{{Symbol: "USDC", Address: testUSDC, PriceSource: "chainlink:" + testWETH, MaxPriceAge: "25h"}}, false},
+		{"invalid price age", []Token{{Symbol: "USDC", Address: testUSDC, PriceSource: "chainlink:" + testWETH, MaxPriceAge: "1 day"}}, true},
+		{"negative price age", []Token{{Symbol: "USDC", Address: testUSDC, PriceSource: "chainlink:" + testWETH, MaxPriceAge: "-1h"}}, true},
+		{"price age without chainlink", []Token{{Symbol: "USDC", Address: testUSDC, PriceSource: "fixed:1", MaxPriceAge: "25h"}}, true},
+	}
+	for _, tt := range tests {
+		registry := &Registry{Network: "sepolia", ChainID: 11155111, Tokens: tt.tokens}
+		if err := registry.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLoadRejectsInvalidRegistry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	data := `{"network": "sepolia", "chainId": 11155111, "tokens": [{"symbol": "USDC", "address": "` + testUSDC + `", "priceSource": "fixed:0"}]}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("expected error for a zero fixed price")
+	}
+}
+
+func TestAssetsOrdering(t *testing.T) {
+	registry := &Registry{Tokens: []Token{
+		{Symbol: "WETH", Address: testWETH, Decimals: decimals(18)},
+		{Symbol: "USDC", Address: testUSDC, Decimals: decimals(6)},
+	}}
+
+	// Every token has its decimals, so nothing is read from the chain
+	assets, err := registry.Assets(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{"1": "ETH", "2": "WETH", "3": "USDC"}
+	if len(assets) != len(want) {
+		t.Fatalf("got %d assets, want %d", len(assets), len(want))
+	}
+	for key, symbol := range want {
+		if a, ok := assets[key]; !ok || a.Symbol() != symbol {
+			t.Errorf("asset %s = %v, want %s", key, a, symbol)
+		}
+	}
+	if got := assets["3"].Decimals(); got != 6 {
+		t.Errorf("USDC decimals = %d, want 6", got)
+	}
+}
+
+func TestTokenLookup(t *testing.T) {
+	registry := &Registry{Tokens: []Token{{Symbol: "USDC", Address: testUSDC}}}
+	if token, ok := registry.Token("usdc"); !ok || token.Address != testUSDC {
+		t.Errorf("Token(usdc) = %+v, %v", token, ok)
+	}
+	if _, ok := registry.Token("DAI"); ok {
+		t.Error("found a token that is not in the registry")
+	}
+}
+
+// newFeedNode serves a Chainlink aggregator with 8 decimals whose latest round answered price at updatedAt
+func newFeedNode(t *testing.T, price int64, updatedAt time.Time) *ethclient.Client {
+	t.Helper()
+	word := func(v int64) []byte { return common.LeftPadBytes(big.NewInt(v).Bytes(), 32) }
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			ID     json.RawMessage   `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Method != "eth_call" {
+			t.Errorf("unexpected request %s: %v", request.Method, err)
+		}
+
+		var call struct {
+			Input hexutil.Bytes `json:"input"`
+			Data  hexutil.Bytes `json:"data"`
+		}
+		json.Unmarshal(request.Params[0], &call)
+		input := append(call.Input, call.Data...)
+
+		var result []byte
+		switch {
+		case strings.HasPrefix(hexutil.Encode(input), "0x313ce567"): // decimals()
+			result = word(8)
+		default: // latestRoundData()
+			result = append(append(append(append(word(1), word(price*100_000_000)...), word(updatedAt.Unix())...), word(updatedAt.Unix())...), word(1)...)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":"%s"}`, request.ID, hexutil.Encode(result))
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := ethclient.Dial(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(client.Close)
+	return client
+}
+
+func TestPriceOracleRejectsStaleChainlinkToken(t *testing.T) {
+	registry := &Registry{Tokens: []Token{
+		{Symbol: "USDC", Address: testUSDC, PriceSource: "chainlink:" + testWETH},
+	}}
+	eth := &oracle.StaticOracle{Prices: map[string]float64{oracle.Pair("ETH", "USD"): 2000}}
+
+	fresh, err := registry.PriceOracle(newFeedNode(t, 1, time.Now().Add(-time.Minute)), eth, eth, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	price, err := fresh.Price(context.Background(), "USDC", "USD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if price.Value != 1 {
+		t.Errorf("price = %f, want 1", price.Value)
+	}
+
+	stale, err := registry.PriceOracle(newFeedNode(t, 1, time.Now().Add(-2*time.Hour)), eth, eth, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stale.Price(context.Background(), "USDC", "USD"); err == nil || !strings.Contains(err.Error(), "stale") {
+		t.Errorf("err = %v, want a stale price error", err)
+	}
+}
+
+func TestPriceOracleUsesTokenPriceAge(t *testing.T) {
+	// USDC/USD updates once a day, so an answer from 20 hours ago is current for it
+	registry := &Registry{Tokens: []Token{
+		{Symbol: "USDC", Address: testUSDC, PriceSource: "chainlink:" + testWETH, MaxPriceAge: "25h"},
+	}}
+	eth := &oracle.StaticOracle{Prices: map[string]float64{oracle.Pair("ETH", "USD"): 2000}}
+	client := newFeedNode(t, 1, time.Now().Add(-20*time.Hour))
+
+	priceOracle, err := registry.PriceOracle(client, eth, eth, 2*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := priceOracle.Price(context.Background(), "USDC", "USD"); err != nil {
+		t.Errorf("err = %v, want the token's 25h price age to accept the answer", err)
+	}
+}
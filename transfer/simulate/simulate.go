@@ -0,0 +1,164 @@
+// transfer/simulate/simulate.go
+
+package simulate
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	errorSelector = []byte{0x08, 0xc3, 0x79, 0xa0} // Error(string)
+	panicSelector = []byte{0x4e, 0x48, 0x7b, 0x71} // Panic(uint256)
+)
+
+// RevertError is returned when the simulated transaction would fail on chain
+type RevertError struct {
+	Reason string
+	Data   []byte
+}
+
+func (e *RevertError) Error() string {
+	return "transaction would revert: " + e.Reason
+}
+
+// Transaction runs tx as sent by from with eth_call against the pending block.
+// contractABI, which may be nil, decodes custom errors and lets a method that returns
+// false, as some ERC-20 tokens do instead of reverting, count as a failure.
+func Transaction(ctx context.Context, client *ethclient.Client, tx *types.Transaction, from common.Address, contractABI *abi.ABI) error {
+	msg := ethereum.CallMsg{
+		From:  from,
+		To:    tx.To(),
+		Gas:   tx.Gas(),
+		Value: tx.Value(),
+		Data:  tx.Data(),
+	}
+	if tx.Type() == types.LegacyTxType {
+		msg.GasPrice = tx.GasPrice()
+	} else {
+		msg.GasTipCap = tx.GasTipCap()
+		msg.GasFeeCap = tx.GasFeeCap()
+	}
+	return Call(ctx, client, msg, contractABI)
+}
+
+// Call runs msg with eth_call against the pending block and returns a *RevertError
+// with the decoded reason when it would revert
+func Call(ctx context.Context, client *ethclient.Client, msg ethereum.CallMsg, contractABI *abi.ABI) error {
+	output, err := client.PendingCallContract(ctx, msg)
+	if err != nil {
+		if data, ok := revertData(err); ok {
+			return &RevertError{Reason: DecodeRevert(data, contractABI), Data: data}
+		}
+		if strings.Contains(err.Error(), "execution reverted") {
+			return &RevertError{Reason: "execution reverted without a reason"}
+		}
+		return fmt.Errorf("simulation failed: %w", err)
+	}
+
+	if method := calledMethod(msg.Data, contractABI); method != nil && returnsFalse(method, output) {
+		return &RevertError{Reason: method.Name + " returned false"}
+	}
+	return nil
+}
+
+// DecodeRevert renders revert data as Error(string) and Panic(uint256) reasons, or as a
+// custom error from contractABI such as ERC20InsufficientBalance(sender=0x…, balance=0, needed=5)
+func DecodeRevert(data []byte, contractABI *abi.ABI) string {
+	if len(data) == 0 {
+		return "execution reverted without a reason"
+	}
+	if len(data) < 4 {
+		return "malformed revert data " + hexutil.Encode(data)
+	}
+
+	switch {
+	case bytes.Equal(data[:4], errorSelector):
+		if reason, err := abi.UnpackRevert(data); err == nil {
+			return reason
+		}
+	case bytes.Equal(data[:4], panicSelector):
+		if reason, err := abi.UnpackRevert(data); err == nil {
+			return "panic: " + reason
+		}
+	}
+
+	if contractABI != nil {
+		for _, customError := range contractABI.Errors {
+			if !bytes.Equal(data[:4], customError.ID[:4]) {
+				continue
+			}
+			values, err := customError.Unpack(data)
+			if err != nil {
+				break
+			}
+			return formatCustomError(customError, values)
+		}
+	}
+	return "unknown error " + hexutil.Encode(data)
+}
+
+func formatCustomError(customError abi.Error, values interface{}) string {
+	args, _ := values.([]interface{})
+	parts := make([]string, len(customError.Inputs))
+	for i, input := range customError.Inputs {
+		var value interface{} = "?"
+		if i < len(args) {
+			value = args[i]
+		}
+		if address, ok := value.(common.Address); ok {
+			value = address.Hex()
+		}
+		parts[i] = fmt.Sprintf("%s=%v", input.Name, value)
+	}
+	return customError.Name + "(" + strings.Join(parts, ", ") + ")"
+}
+
+// revertData extracts the revert payload that geth-compatible nodes attach to call errors
+func revertData(err error) ([]byte, bool) {
+	var dataError rpc.DataError
+	if !errors.As(err, &dataError) {
+		return nil, false
+	}
+	encoded, ok := dataError.ErrorData().(string)
+	if !ok {
+		return nil, false
+	}
+	data, err := hexutil.Decode(encoded)
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+func calledMethod(data []byte, contractABI *abi.ABI) *abi.Method {
+	if contractABI == nil || len(data) < 4 {
+		return nil
+	}
+	method, err := contractABI.MethodById(data[:4])
+	if err != nil {
+		return nil
+	}
+	return method
+}
+
+// returnsFalse reports whether a method with a single bool output returned false.
+// Tokens like USDT return nothing at all, which is treated as success.
+func returnsFalse(method *abi.Method, output []byte) bool {
+	if len(method.Outputs) != 1 || method.Outputs[0].Type.T != abi.BoolTy || len(output) == 0 {
+		return false
+	}
+	return new(big.Int).SetBytes(output).Sign() == 0
+}
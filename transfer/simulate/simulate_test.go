@@ -0,0 +1,147 @@
+// transfer/simulate/simulate_test.go
+
+package simulate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+const testABI = `[
+	{"inputs":[{"name":"to","type":"address"},{"name":"value","type":"uint256"}],"name":"transfer","outputs":[{"name":"","type":"bool"}],"stateMutability":"nonpayable","type":"function"},
+	{"inputs":[{"name":"sender","type":"address"},{"name":"balance","type":"uint256"},{"name":"needed","type":"uint256"}],"name":"ERC20InsufficientBalance","type":"error"}
+]`
+
+var sender = common.HexToAddress("0x00000000000000000000000000000000000000aa")
+
+func parseABI(t *testing.T) *abi.ABI {
+	t.Helper()
+	contractABI, err := abi.JSON(strings.NewReader(testABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &contractABI
+}
+
+func pack(t *testing.T, selector []byte, types []string, values ...interface{}) []byte {
+	t.Helper()
+	var args abi.Arguments
+	for _, name := range types {
+		typ, err := abi.NewType(name, "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		args = append(args, abi.Argument{Type: typ})
+	}
+	encoded, err := args.Pack(values...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append(append([]byte{}, selector...), encoded...)
+}
+
+func TestDecodeRevert(t *testing.T) {
+	contractABI := parseABI(t)
+	insufficient := contractABI.Errors["ERC20InsufficientBalance"]
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"error string", pack(t, errorSelector, []string{"string"}, "transfer amount exceeds balance"), "transfer amount exceeds balance"},
+		{"panic", pack(t, panicSelector, []string{"uint256"}, big.NewInt(0x11)), "panic: arithmetic underflow or overflow"},
+		{"custom error", pack(t, insufficient.ID[:4], []string{"address", "uint256", "uint256"}, sender, big.NewInt(5), big.NewInt(10)),
+			"ERC20InsufficientBalance(sender=" + sender.Hex() + ", balance=5, needed=10)"},
+		{"unknown", []byte{0xde, 0xad, 0xbe, 0xef}, "unknown error 0xdeadbeef"},
+		{"empty", nil, "execution reverted without a reason"},
+		{"malformed", []byte{0x01}, "malformed revert data 0x01"},
+	}
+	for _, tt := range tests {
+		if got := DecodeRevert(tt.data, contractABI); got != tt.want {
+			t.Errorf("%s: DecodeRevert = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	// Without the ABI a custom error cannot be named
+	data := pack(t, insufficient.ID[:4], []string{"address", "uint256", "uint256"}, sender, big.NewInt(5), big.NewInt(10))
+	if got := DecodeRevert(data, nil); !strings.HasPrefix(got, "unknown error 0x") {
+		t.Errorf("DecodeRevert without ABI = %q", got)
+	}
+}
+
+// newNode serves eth_call with the given JSON-RPC result or error member
+func newNode(t *testing.T, response string) *ethclient.Client {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(body), `"eth_call"`) || !strings.Contains(string(body), `"pending"`) {
+			t.Errorf("unexpected request %s", body)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":1,%s}`, response)
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := ethclient.Dial(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(client.Close)
+	return client
+}
+
+func TestCall(t *testing.T) {
+	contractABI := parseABI(t)
+	token := common.HexToAddress("0x00000000000000000000000000000000000000bb")
+	data, err := contractABI.Pack("transfer", sender, big.NewInt(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := ethereum.CallMsg{From: sender, To: &token, Data: data}
+
+	reverted := pack(t, errorSelector, []string{"string"}, "paused")
+	tests := []struct {
+		name     string
+		response string
+		want     string
+	}{
+		{"success", `"result":"0x0000000000000000000000000000000000000000000000000000000000000001"`, ""},
+		{"no return data", `"result":"0x"`, ""},
+		{"returns false", `"result":"0x0000000000000000000000000000000000000000000000000000000000000000"`, "transaction would revert: transfer returned false"},
+		{"revert reason", `"error":{"code":3,"message":"execution reverted: paused","data":"` + hexutil.Encode(reverted) + `"}`, "transaction would revert: paused"},
+		{"bare revert", `"error":{"code":-32000,"message":"execution reverted"}`, "transaction would revert: execution reverted without a reason"},
+		{"other failure", `"error":{"code":-32000,"message":"insufficient funds for gas * price + value"}`, "simulation failed: insufficient funds for gas * price + value"},
+	}
+	for _, tt := range tests {
+		err := Call(context.Background(), newNode(t, tt.response), msg, contractABI)
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+
+	err = Call(context.Background(), newNode(t, `"error":{"code":3,"message":"execution reverted","data":"`+hexutil.Encode(reverted)+`"}`), msg, contractABI)
+	var revertErr *RevertError
+	if !errors.As(err, &revertErr) || hexutil.Encode(revertErr.Data) != hexutil.Encode(reverted) {
+		t.Errorf("err = %v, want *RevertError carrying the revert data", err)
+	}
+}
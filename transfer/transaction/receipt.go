@@ -0,0 +1,223 @@
+// transaction/receipt.go
+
+package transaction
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Status is the outcome of a mined transaction
+type Status string
+
+const (
+	StatusSuccessful Status = "successful"
+	StatusReverted   Status = "reverted"
+)
+
+var (
+	// ErrReplaced is returned when another transaction with the same nonce was mined instead
+	ErrReplaced = errors.New("transaction was replaced by another with the same nonce")
+	// ErrDropped is returned when the node no longer knows the transaction and it was never mined
+	ErrDropped = errors.New("transaction was dropped from the mempool")
+)
+
+const (
+	defaultPollInterval = 4 * time.Second
+	defaultWaitTimeout  = 10 * time.Minute
+	// droppedAfterMisses is how many polls in a row must miss the transaction before it counts as
+	// dropped, so that load-balanced nodes which have not seen it yet are not mistaken for a drop
+	droppedAfterMisses = 3
+)
+
+// WaitOptions controls WaitForReceipt. Zero values use a 4s poll interval, a 10 minute timeout
+// and a single confirmation. Subscribe listens for new heads as well as polling, which needs a
+// websocket or IPC endpoint; it falls back to polling alone when the node does not support it.
+type WaitOptions struct {
+	Confirmations uint64
+	PollInterval  time.Duration
+	Timeout       time.Duration
+	Subscribe     bool
+}
+
+// ReceiptBackend is the part of the node WaitForReceipt reads from. *ethclient.Client implements it.
+type ReceiptBackend interface {
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+	TransactionByHash(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error)
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+	BlockNumber(ctx context.Context) (uint64, error)
+	HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error)
+}
+
+// headSubscriber is implemented by backends that can push new heads, like *ethclient.Client
+type headSubscriber interface {
+	SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error)
+}
+
+// Result describes a mined transaction. Confirmations counts the inclusion block as the first.
+type Result struct {
+	TxHash            common.Hash
+	BlockNumber       uint64
+	BlockHash         common.Hash
+	GasUsed           uint64
+	EffectiveGasPrice *big.Int
+	Status            Status
+	Confirmations     uint64
+}
+
+// Fee is the amount paid for gas in wei
+func (r *Result) Fee() *big.Int {
+	return new(big.Int).Mul(r.EffectiveGasPrice, new(big.Int).SetUint64(r.GasUsed))
+}
+
+// WaitForReceipt blocks until tx is mined with the requested number of confirmations,
+// the transaction is replaced or dropped, or the timeout expires
+func WaitForReceipt(ctx context.Context, client ReceiptBackend, tx *types.Transaction, opts WaitOptions) (*Result, error) {
+	if opts.Confirmations == 0 {
+		opts.Confirmations = 1
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = defaultPollInterval
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultWaitTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	sender, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to recover sender: %w", err)
+	}
+
+	var heads chan *types.Header
+	var subErr <-chan error
+	if subscriber, ok := client.(headSubscriber); ok && opts.Subscribe {
+		heads = make(chan *types.Header, 1)
+		sub, err := subscriber.SubscribeNewHead(ctx, heads)
+		if err == nil {
+			defer sub.Unsubscribe()
+			subErr = sub.Err()
+		} else {
+			heads = nil
+		}
+	}
+
+	ticker := time.NewTicker(opts.PollInterval)
+	defer ticker.Stop()
+
+	misses := 0
+	for {
+		result, err := checkReceipt(ctx, client, tx, sender, &misses)
+		if err != nil {
+			return nil, err
+		}
+		if result != nil && result.Confirmations >= opts.Confirmations {
+			return result, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if result != nil {
+				return result, fmt.Errorf("timed out with %d of %d confirmations: %w", result.Confirmations, opts.Confirmations, ctx.Err())
+			}
+			return nil, fmt.Errorf("timed out waiting for transaction %s: %w", tx.Hash().Hex(), ctx.Err())
+		case <-ticker.C:
+		case <-heads:
+		case <-subErr:
+			// The subscription broke; keep going on the ticker alone
+			heads, subErr = nil, nil
+		}
+	}
+}
+
+// checkReceipt returns the current result for a mined transaction, nil while it is pending,
+// or an error once it has been replaced or dropped
+func checkReceipt(ctx context.Context, client ReceiptBackend, tx *types.Transaction, sender common.Address, misses *int) (*Result, error) {
+	receipt, err := client.TransactionReceipt(ctx, tx.Hash())
+	if err == nil {
+		head, err := client.BlockNumber(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get block number: %w", err)
+		}
+		if receipt.EffectiveGasPrice == nil {
+			if receipt.EffectiveGasPrice, err = effectiveGasPrice(ctx, client, tx, receipt); err != nil {
+				return nil, err
+			}
+		}
+		return newResult(receipt, head), nil
+	}
+	if !errors.Is(err, ethereum.NotFound) {
+		return nil, fmt.Errorf("failed to get receipt: %w", err)
+	}
+
+	nonce, err := client.NonceAt(ctx, sender, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get nonce: %w", err)
+	}
+	if nonce > tx.Nonce() {
+		// The nonce may have been used by this transaction in a block the node has not indexed yet
+		if _, err := client.TransactionReceipt(ctx, tx.Hash()); err == nil {
+			return nil, nil
+		}
+		return nil, ErrReplaced
+	}
+
+	if _, _, err := client.TransactionByHash(ctx, tx.Hash()); errors.Is(err, ethereum.NotFound) {
+		*misses++
+		if *misses >= droppedAfterMisses {
+			return nil, ErrDropped
+		}
+	} else {
+		*misses = 0
+	}
+	return nil, nil
+}
+
+// effectiveGasPrice works out the price paid for nodes that leave it out of the receipt
+func effectiveGasPrice(ctx context.Context, client ReceiptBackend, tx *types.Transaction, receipt *types.Receipt) (*big.Int, error) {
+	if tx.Type() == types.LegacyTxType {
+		return tx.GasPrice(), nil
+	}
+
+	header, err := client.HeaderByHash(ctx, receipt.BlockHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block header: %w", err)
+	}
+	tip, err := tx.EffectiveGasTip(header.BaseFee)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute effective gas price: %w", err)
+	}
+	return tip.Add(tip, header.BaseFee), nil
+}
+
+func newResult(receipt *types.Receipt, head uint64) *Result {
+	status := StatusSuccessful
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		status = StatusReverted
+	}
+
+	block := receipt.BlockNumber.Uint64()
+	var confirmations uint64
+	if head >= block {
+		confirmations = head - block + 1
+	}
+
+	return &Result{
+		TxHash:            receipt.TxHash,
+		BlockNumber:       block,
+		BlockHash:         receipt.BlockHash,
+		GasUsed:           receipt.GasUsed,
+		EffectiveGasPrice: receipt.EffectiveGasPrice,
+		Status:            status,
+		Confirmations:     confirmations,
+	}
+}
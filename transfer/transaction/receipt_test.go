@@ -0,0 +1,204 @@
+// transaction/receipt_test.go
+
+package transaction
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestNewResult(t *testing.T) {
+	receipt := &types.Receipt{
+		Status:            types.ReceiptStatusSuccessful,
+		BlockNumber:       big.NewInt(100),
+		GasUsed:           21000,
+		EffectiveGasPrice: big.NewInt(2_000_000_000),
+	}
+
+	tests := []struct {
+		head uint64
+		want uint64
+	}{
+		{99, 0},
+		{100, 1},
+		{105, 6},
+	}
+	for _, tt := range tests {
+		if got := newResult(receipt, tt.head).Confirmations; got != tt.want {
+			t.Errorf("head %d: confirmations = %d, want %d", tt.head, got, tt.want)
+		}
+	}
+
+	result := newResult(receipt, 100)
+	if result.Status != StatusSuccessful {
+		t.Errorf("status = %s, want %s", result.Status, StatusSuccessful)
+	}
+	if fee := result.Fee(); fee.Cmp(big.NewInt(42_000_000_000_000)) != 0 {
+		t.Errorf("fee = %s, want 42000000000000", fee)
+	}
+
+	receipt.Status = types.ReceiptStatusFailed
+	if got := newResult(receipt, 100).Status; got != StatusReverted {
+		t.Errorf("status = %s, want %s", got, StatusReverted)
+	}
+}
+
+// fakeChain answers receipt lookups for one transaction. The receipt stays hidden until
+// indexedAfter lookups have been made, which mimics a node that indexes receipts late.
+type fakeChain struct {
+	receipt      *types.Receipt
+	indexedAfter int
+	lookups      int
+	nonce        uint64
+	inMempool    bool
+	head         uint64
+	baseFee      *big.Int
+}
+
+func (f *fakeChain) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	f.lookups++
+	if f.receipt == nil || f.lookups <= f.indexedAfter {
+		return nil, ethereum.NotFound
+	}
+	receipt := *f.receipt
+	return &receipt, nil
+}
+
+func (f *fakeChain) TransactionByHash(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+	if !f.inMempool {
+		return nil, false, ethereum.NotFound
+	}
+	return nil, true, nil
+}
+
+func (f *fakeChain) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	return f.nonce, nil
+}
+
+func (f *fakeChain) BlockNumber(ctx context.Context) (uint64, error) {
+	return f.head, nil
+}
+
+func (f *fakeChain) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	return &types.Header{BaseFee: f.baseFee}, nil
+}
+
+func signedTestTx(t *testing.T) (*types.Transaction, common.Address) {
+	t.Helper()
+	key, _ := crypto.GenerateKey()
+	tx, err := types.SignTx(dynamicTx(), types.LatestSignerForChainID(big.NewInt(11155111)), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx, crypto.PubkeyToAddress(key.PublicKey)
+}
+
+func minedReceipt(tx *types.Transaction) *types.Receipt {
+	return &types.Receipt{
+		Status:      types.ReceiptStatusSuccessful,
+		TxHash:      tx.Hash(),
+		BlockNumber: big.NewInt(100),
+		GasUsed:     21000,
+	}
+}
+
+var fastWait = WaitOptions{PollInterval: time.Millisecond, Timeout: 5 * time.Second}
+
+func TestCheckReceiptMined(t *testing.T) {
+	tx, sender := signedTestTx(t)
+	chain := &fakeChain{receipt: minedReceipt(tx), nonce: 8, head: 102, baseFee: gwei(10)}
+
+	misses := 0
+	result, err := checkReceipt(context.Background(), chain, tx, sender, &misses)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result == nil || result.Confirmations != 3 {
+		t.Fatalf("result = %+v, want 3 confirmations", result)
+	}
+	// The receipt leaves out the price, so it is the base fee plus the 2 gwei tip
+	if result.EffectiveGasPrice.Cmp(gwei(12)) != 0 {
+		t.Errorf("effective gas price = %s, want %s", result.EffectiveGasPrice, gwei(12))
+	}
+}
+
+func TestCheckReceiptReplaced(t *testing.T) {
+	tx, sender := signedTestTx(t)
+	chain := &fakeChain{nonce: tx.Nonce() + 1}
+
+	misses := 0
+	if _, err := checkReceipt(context.Background(), chain, tx, sender, &misses); !errors.Is(err, ErrReplaced) {
+		t.Errorf("err = %v, want ErrReplaced", err)
+	}
+	if _, err := WaitForReceipt(context.Background(), chain, tx, fastWait); !errors.Is(err, ErrReplaced) {
+		t.Errorf("wait: err = %v, want ErrReplaced", err)
+	}
+}
+
+func TestCheckReceiptLaggingIndex(t *testing.T) {
+	tx, sender := signedTestTx(t)
+	// The nonce is used, but the first lookup misses the receipt; the second finds it
+	chain := &fakeChain{receipt: minedReceipt(tx), indexedAfter: 1, nonce: tx.Nonce() + 1, head: 100, baseFee: gwei(10)}
+
+	misses := 0
+	result, err := checkReceipt(context.Background(), chain, tx, sender, &misses)
+	if err != nil || result != nil {
+		t.Fatalf("result = %+v, err = %v; want pending while the receipt is being indexed", result, err)
+	}
+
+	chain.lookups = 0
+	result, err = WaitForReceipt(context.Background(), chain, tx, fastWait)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.TxHash != tx.Hash() || result.Status != StatusSuccessful {
+		t.Errorf("result = %+v, want the mined transaction", result)
+	}
+}
+
+func TestCheckReceiptDropped(t *testing.T) {
+	tx, sender := signedTestTx(t)
+	chain := &fakeChain{nonce: tx.Nonce()}
+
+	misses := 0
+	for i := 1; i < droppedAfterMisses; i++ {
+		if _, err := checkReceipt(context.Background(), chain, tx, sender, &misses); err != nil {
+			t.Fatalf("miss %d: %v", i, err)
+		}
+	}
+
+	// Seeing the transaction again resets the count
+	chain.inMempool = true
+	if _, err := checkReceipt(context.Background(), chain, tx, sender, &misses); err != nil || misses != 0 {
+		t.Fatalf("misses = %d, err = %v; want a reset", misses, err)
+	}
+
+	chain.inMempool = false
+	if _, err := WaitForReceipt(context.Background(), chain, tx, fastWait); !errors.Is(err, ErrDropped) {
+		t.Errorf("wait: err = %v, want ErrDropped", err)
+	}
+}
+
+func TestWaitForReceiptConfirmations(t *testing.T) {
+	tx, _ := signedTestTx(t)
+	chain := &fakeChain{receipt: minedReceipt(tx), nonce: 8, head: 100, baseFee: gwei(10)}
+
+	opts := fastWait
+	opts.Confirmations = 3
+	opts.Timeout = 50 * time.Millisecond
+	result, err := WaitForReceipt(context.Background(), chain, tx, opts)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want a timeout short of the confirmations", err)
+	}
+	if result == nil || result.Confirmations != 1 {
+		t.Errorf("result = %+v, want the receipt with 1 confirmation", result)
+	}
+}
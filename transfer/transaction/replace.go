@@ -0,0 +1,115 @@
+// transaction/replace.go
+
+package transaction
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"go-ethereum-wallet/transfer/ethereum_client"
+)
+
+// MinReplacementBump is the fee increase, in percent, that geth's mempool requires
+// before it accepts a transaction with the same nonce as a pending one
+const MinReplacementBump = 10
+
+// SpeedUp returns tx with the same nonce, recipient, value and data, paying the higher of
+// the bumped original fees and current
+func SpeedUp(tx *types.Transaction, current *ethereum_client.GasFees) (*types.Transaction, error) {
+	return replace(tx, current, tx.To(), tx.Value(), tx.Gas(), tx.Data())
+}
+
+// Cancel returns a zero-value transfer from sender to itself at tx's nonce, so the original can
+// no longer be mined. It pays the higher of the bumped original fees and current.
+func Cancel(tx *types.Transaction, sender common.Address, current *ethereum_client.GasFees) (*types.Transaction, error) {
+	return replace(tx, current, &sender, new(big.Int), params.TxGas, nil)
+}
+
+// CancelNonce returns a zero-value transfer from sender to itself at nonce paying current fees.
+// It fills a nonce whose own transaction is unknown, so there are no fees to outbid.
+func CancelNonce(chainID *big.Int, nonce uint64, sender common.Address, current *ethereum_client.GasFees) *types.Transaction {
+	if !current.IsDynamic() {
+		return types.NewTx(&types.LegacyTx{
+			Nonce:    nonce,
+			To:       &sender,
+			Value:    new(big.Int),
+			Gas:      params.TxGas,
+			GasPrice: current.GasPrice,
+		})
+	}
+	return types.NewTx(&types.DynamicFeeTx{
+		ChainID:   chainID,
+		Nonce:     nonce,
+		To:        &sender,
+		Value:     new(big.Int),
+		Gas:       params.TxGas,
+		GasTipCap: current.GasTipCap,
+		GasFeeCap: current.GasFeeCap,
+	})
+}
+
+// ReplacementFees returns the fees a replacement for tx must pay: at least MinReplacementBump
+// percent above the original and never below current
+func ReplacementFees(tx *types.Transaction, current *ethereum_client.GasFees) *ethereum_client.GasFees {
+	if tx.Type() == types.LegacyTxType {
+		return &ethereum_client.GasFees{GasPrice: maxBig(bump(tx.GasPrice()), current.MaxGasPrice())}
+	}
+
+	currentTip, currentCap := current.GasTipCap, current.GasFeeCap
+	if !current.IsDynamic() {
+		currentTip, currentCap = current.GasPrice, current.GasPrice
+	}
+
+	tip := maxBig(bump(tx.GasTipCap()), currentTip)
+	feeCap := maxBig(bump(tx.GasFeeCap()), currentCap)
+	if feeCap.Cmp(tip) < 0 {
+		feeCap = new(big.Int).Set(tip)
+	}
+	return &ethereum_client.GasFees{GasTipCap: tip, GasFeeCap: feeCap}
+}
+
+func replace(tx *types.Transaction, current *ethereum_client.GasFees, to *common.Address, value *big.Int, gas uint64, data []byte) (*types.Transaction, error) {
+	if to == nil {
+		return nil, errors.New("contract creations cannot be replaced")
+	}
+	fees := ReplacementFees(tx, current)
+
+	if tx.Type() == types.LegacyTxType {
+		return types.NewTx(&types.LegacyTx{
+			Nonce:    tx.Nonce(),
+			To:       to,
+			Value:    value,
+			Gas:      gas,
+			GasPrice: fees.GasPrice,
+			Data:     data,
+		}), nil
+	}
+
+	return types.NewTx(&types.DynamicFeeTx{
+		ChainID:   tx.ChainId(),
+		Nonce:     tx.Nonce(),
+		To:        to,
+		Value:     value,
+		Gas:       gas,
+		GasTipCap: fees.GasTipCap,
+		GasFeeCap: fees.GasFeeCap,
+		Data:      data,
+	}), nil
+}
+
+// bump raises value by MinReplacementBump percent, rounding up so the node's check always passes
+func bump(value *big.Int) *big.Int {
+	bumped := new(big.Int).Mul(value, big.NewInt(100+MinReplacementBump))
+	bumped.Add(bumped, big.NewInt(99))
+	return bumped.Div(bumped, big.NewInt(100))
+}
+
+func maxBig(a, b *big.Int) *big.Int {
+	if b != nil && b.Cmp(a) > 0 {
+		return new(big.Int).Set(b)
+	}
+	return new(big.Int).Set(a)
+}
@@ -0,0 +1,109 @@
+// transaction/replace_test.go
+
+package transaction
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"go-ethereum-wallet/transfer/ethereum_client"
+)
+
+var (
+	testSender    = common.HexToAddress("0x9858EfFD232B4033E47d90003D41EC34EcaEda94")
+	testRecipient = common.HexToAddress("0x000000000000000000000000000000000000dEaD")
+)
+
+func gwei(n int64) *big.Int {
+	return new(big.Int).Mul(big.NewInt(n), big.NewInt(1_000_000_000))
+}
+
+func dynamicTx() *types.Transaction {
+	return types.NewTx(&types.DynamicFeeTx{
+		ChainID:   big.NewInt(11155111),
+		Nonce:     7,
+		To:        &testRecipient,
+		Value:     big.NewInt(1000),
+		Gas:       60000,
+		GasTipCap: gwei(2),
+		GasFeeCap: gwei(30),
+		Data:      []byte{0xa9, 0x05, 0x9c, 0xbb},
+	})
+}
+
+func TestSpeedUpBumpsFees(t *testing.T) {
+	tx := dynamicTx()
+	replacement, err := SpeedUp(tx, &ethereum_client.GasFees{GasTipCap: gwei(1), GasFeeCap: gwei(20)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if replacement.GasTipCap().Cmp(big.NewInt(2_200_000_000)) != 0 {
+		t.Errorf("tip = %s, want 2200000000", replacement.GasTipCap())
+	}
+	if replacement.GasFeeCap().Cmp(gwei(33)) != 0 {
+		t.Errorf("fee cap = %s, want 33 gwei", replacement.GasFeeCap())
+	}
+	if replacement.Nonce() != tx.Nonce() || *replacement.To() != *tx.To() || replacement.Value().Cmp(tx.Value()) != 0 ||
+		replacement.Gas() != tx.Gas() || string(replacement.Data()) != string(tx.Data()) {
+		t.Error("speed-up changed more than the fees")
+	}
+}
+
+func TestSpeedUpUsesCurrentFeesWhenHigher(t *testing.T) {
+	replacement, err := SpeedUp(dynamicTx(), &ethereum_client.GasFees{GasTipCap: gwei(5), GasFeeCap: gwei(80)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replacement.GasTipCap().Cmp(gwei(5)) != 0 || replacement.GasFeeCap().Cmp(gwei(80)) != 0 {
+		t.Errorf("fees = %s/%s, want the current 5/80 gwei", replacement.GasTipCap(), replacement.GasFeeCap())
+	}
+}
+
+func TestCancel(t *testing.T) {
+	tx := dynamicTx()
+	replacement, err := Cancel(tx, testSender, &ethereum_client.GasFees{GasTipCap: gwei(1), GasFeeCap: gwei(20)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *replacement.To() != testSender {
+		t.Errorf("to = %s, want the sender", replacement.To().Hex())
+	}
+	if replacement.Value().Sign() != 0 || len(replacement.Data()) != 0 || replacement.Gas() != 21000 {
+		t.Errorf("cancel should be a plain zero-value transfer, got value %s, gas %d", replacement.Value(), replacement.Gas())
+	}
+	if replacement.Nonce() != tx.Nonce() {
+		t.Errorf("nonce = %d, want %d", replacement.Nonce(), tx.Nonce())
+	}
+}
+
+func TestCancelNonce(t *testing.T) {
+	fees := &ethereum_client.GasFees{GasTipCap: gwei(1), GasFeeCap: gwei(20)}
+	tx := CancelNonce(big.NewInt(11155111), 4, testSender, fees)
+	if tx.Nonce() != 4 || *tx.To() != testSender || tx.Value().Sign() != 0 || tx.Gas() != 21000 {
+		t.Errorf("got nonce %d to %s value %s gas %d; want a zero-value self-transfer at nonce 4", tx.Nonce(), tx.To().Hex(), tx.Value(), tx.Gas())
+	}
+	if tx.GasTipCap().Cmp(gwei(1)) != 0 || tx.GasFeeCap().Cmp(gwei(20)) != 0 || tx.ChainId().Cmp(big.NewInt(11155111)) != 0 {
+		t.Errorf("fees = %s/%s on chain %s, want the current fees on chain 11155111", tx.GasTipCap(), tx.GasFeeCap(), tx.ChainId())
+	}
+
+	legacy := CancelNonce(big.NewInt(1), 4, testSender, &ethereum_client.GasFees{GasPrice: gwei(3)})
+	if legacy.Type() != types.LegacyTxType || legacy.GasPrice().Cmp(gwei(3)) != 0 {
+		t.Errorf("legacy fees should give a legacy transaction at the gas price, got type %d price %s", legacy.Type(), legacy.GasPrice())
+	}
+}
+
+func TestReplacementFeesLegacy(t *testing.T) {
+	tx := types.NewTx(&types.LegacyTx{Nonce: 1, To: &testRecipient, Gas: 21000, GasPrice: big.NewInt(15)})
+
+	fees := ReplacementFees(tx, &ethereum_client.GasFees{GasPrice: big.NewInt(1)})
+	if fees.GasPrice.Cmp(big.NewInt(17)) != 0 {
+		t.Errorf("gas price = %s, want 17 (15 bumped by 10%%, rounded up)", fees.GasPrice)
+	}
+	if fees.IsDynamic() {
+		t.Error("legacy replacement should stay legacy")
+	}
+}
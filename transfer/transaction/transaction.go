@@ -5,24 +5,46 @@ package transaction
 import (
 	"context"
 	"crypto/ecdsa"
+	"errors"
 	"fmt"
+	"math/big"
+	"strings"
 
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
 	"go-ethereum-wallet/transfer/logger"
 )
 
-func SendTransaction(client *ethclient.Client, tx *types.Transaction, privateKey *ecdsa.PrivateKey, baseURL string) error {
-	chainID, err := client.NetworkID(context.Background())
+// SendTransaction signs and broadcasts tx, returning the signed transaction for WaitForReceipt
+func SendTransaction(client *ethclient.Client, tx *types.Transaction, privateKey *ecdsa.PrivateKey, baseURL string) (*types.Transaction, error) {
+	chainID, err := client.ChainID(context.Background())
 	if err != nil {
-		return fmt.Errorf("failed to get network ID: %w", err)
+		return nil, fmt.Errorf("failed to get chain ID: %w", err)
 	}
 
-	signedTx, err := types.SignTx(tx, types.NewEIP155Signer(chainID), privateKey)
+	signedTx, err := SignTransaction(tx, chainID, privateKey)
 	if err != nil {
-		return fmt.Errorf("failed to sign transaction: %w", err)
+		return nil, err
 	}
 
+	if err := BroadcastTransaction(client, signedTx, baseURL); err != nil {
+		return nil, err
+	}
+	return signedTx, nil
+}
+
+// SignTransaction signs tx for chainID without touching the network
+func SignTransaction(tx *types.Transaction, chainID *big.Int, privateKey *ecdsa.PrivateKey) (*types.Transaction, error) {
+	signedTx, err := types.SignTx(tx, types.LatestSignerForChainID(chainID), privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign transaction: %w", err)
+	}
+	return signedTx, nil
+}
+
+// BroadcastTransaction sends an already signed transaction and logs its explorer link
+func BroadcastTransaction(client *ethclient.Client, signedTx *types.Transaction, baseURL string) error {
 	if err := client.SendTransaction(context.Background(), signedTx); err != nil {
 		return fmt.Errorf("failed to send transaction: %w", err)
 	}
@@ -33,3 +55,11 @@ func SendTransaction(client *ethclient.Client, tx *types.Transaction, privateKey
 
 	return nil
 }
+
+// Rejected reports whether err from BroadcastTransaction is the node refusing the transaction, so
+// it was certainly not broadcast. A timeout or dropped connection leaves the outcome unknown, and
+// "already known" means the node holds the transaction already.
+func Rejected(err error) bool {
+	var rpcErr rpc.Error
+	return errors.As(err, &rpcErr) && !strings.Contains(err.Error(), "already known")
+}
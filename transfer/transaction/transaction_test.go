@@ -0,0 +1,52 @@
+// transaction/transaction_test.go
+
+package transaction
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+func TestRejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		want    bool
+	}{
+		{"node refuses", rpcResponse(`"error":{"code":-32000,"message":"insufficient funds for gas * price + value"}`), true},
+		{"already known", rpcResponse(`"error":{"code":-32000,"message":"already known"}`), false},
+		{"node unavailable", func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "upstream timed out", http.StatusGatewayTimeout)
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+			client, err := ethclient.Dial(server.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer client.Close()
+
+			err = BroadcastTransaction(client, dynamicTx(), "https://example.invalid")
+			if err == nil {
+				t.Fatal("BroadcastTransaction succeeded")
+			}
+			if got := Rejected(err); got != tt.want {
+				t.Errorf("Rejected(%v) = %v, want %v", err, got, tt.want)
+			}
+		})
+	}
+}
+
+func rpcResponse(member string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":1,%s}`, member)
+	}
+}
@@ -3,19 +3,39 @@ package userinput
 import (
 	"fmt"
 	"go-ethereum-wallet/transfer/asset"
+	"go-ethereum-wallet/transfer/ethereum_client"
+	"math/big"
+	"sort"
+	"strings"
 )
 
 func SelectAsset(assets map[string]asset.Asset) string {
 	var assetChoice string
 	fmt.Println("Select the asset to transfer:")
-	for key, value := range assets {
-		fmt.Printf("%s: %s\n", key, value.Name())
+	for _, key := range sortedKeys(assets) {
+		a := assets[key]
+		if a.Name() == a.Symbol() {
+			fmt.Printf("%s: %s\n", key, a.Symbol())
+		} else {
+			fmt.Printf("%s: %s (%s)\n", key, a.Name(), a.Symbol())
+		}
 	}
 	fmt.Println("Enter the number of your choice: ")
 	fmt.Scanln(&assetChoice)
 	return assetChoice
 }
 
+func SelectGasStrategy(strategies map[string]ethereum_client.GasStrategy) string {
+	var strategyChoice string
+	fmt.Println("Select the gas pricing strategy:")
+	for _, key := range sortedKeys(strategies) {
+		fmt.Printf("%s: %s\n", key, strategies[key].Name())
+	}
+	fmt.Println("Enter the number of your choice: ")
+	fmt.Scanln(&strategyChoice)
+	return strategyChoice
+}
+
 func GetAccountName() string {
 	var accountName string
 	fmt.Print("Enter your account name: ")
@@ -23,6 +43,13 @@ func GetAccountName() string {
 	return accountName
 }
 
+func GetAddressIndex() uint32 {
+	var index uint32
+	fmt.Print("Enter the address index to send from: ")
+	fmt.Scanln(&index)
+	return index
+}
+
 func GetReceiverAddress() string {
 	var receiverAddress string
 	fmt.Print("Enter the receiver's address: ")
@@ -30,16 +57,80 @@ func GetReceiverAddress() string {
 	return receiverAddress
 }
 
-func GetTransferAmount() float64 {
-	var amountInDollars float64
-	fmt.Print("Enter the amount to transfer (in USD): ")
-	fmt.Scanf("%f", &amountInDollars)
-	return amountInDollars
+// GetTransferAmount returns the amount and unit as typed, e.g. "0.5" and "ETH",
+// so the amount can be converted without float rounding
+func GetTransferAmount(units []string) (string, string) {
+	var amount, unit string
+	fmt.Printf("Enter the amount to transfer followed by a unit (%s): ", strings.Join(units, ", "))
+	fmt.Scanln(&amount, &unit)
+	return amount, unit
+}
+
+func GetSenderAddress() string {
+	var senderAddress string
+	fmt.Print("Enter the sender's address: ")
+	fmt.Scanln(&senderAddress)
+	return senderAddress
+}
+
+func GetTransactionFilePath(purpose string) string {
+	var path string
+	fmt.Printf("Enter the path of the %s: ", purpose)
+	fmt.Scanln(&path)
+	return path
+}
+
+func GetPayoutsPath() string {
+	var path string
+	fmt.Print("Enter the path of the payouts CSV file: ")
+	fmt.Scanln(&path)
+	return path
+}
+
+func ConfirmBatch(count int) bool {
+	var confirmation string
+	fmt.Printf("Send all %d payouts? (yes/no): ", count)
+	fmt.Scanln(&confirmation)
+	return confirmation == "yes"
+}
+
+func GetTransactionHash() string {
+	var txHash string
+	fmt.Print("Enter the hash of the pending transaction: ")
+	fmt.Scanln(&txHash)
+	return txHash
+}
+
+func ConfirmCancelNonce(gap uint64) bool {
+	var confirmation string
+	fmt.Printf("Cancel nonce %d with a zero-value transaction to yourself? (yes/no): ", gap)
+	fmt.Scanln(&confirmation)
+	return confirmation == "yes"
 }
 
-func ConfirmTransaction() bool {
+// ConfirmTransaction asks before sending a transaction whose fees were chosen by strategy and
+// cost at most maxFee wei. strategy is empty when the fees were chosen earlier, e.g. offline.
+func ConfirmTransaction(strategy string, maxFee *big.Int) bool {
 	var confirmation string
-	fmt.Print("Are you okay with this increased gas price and transaction fee? (yes/no): ")
+	if strategy != "" {
+		fmt.Printf("Gas strategy: %s\n", strategy)
+	}
+	fmt.Printf("Send this transaction, paying at most %s ETH in fees? (yes/no): ", asset.FormatUnits(maxFee, asset.EtherDecimals))
 	fmt.Scanln(&confirmation)
 	return confirmation == "yes"
 }
+
+// sortedKeys orders numeric menu keys so that "10" follows "9"
+func sortedKeys[V any](choices map[string]V) []string {
+	keys := make([]string, 0, len(choices))
+	for key := range choices {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) < len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}